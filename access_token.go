@@ -1,20 +1,28 @@
 package wego
 
 import (
+	"context"
 	"crypto/md5"
 	"fmt"
 	"github.com/godcong/wego/cache"
 	"github.com/godcong/wego/util"
 	"golang.org/x/xerrors"
 	"strings"
+	"sync"
 )
 
 /*AccessToken GetToken */
 type AccessToken struct {
 	*AccessTokenProperty
-	remoteURL string
-	tokenKey  string
-	tokenURL  string
+	remoteURL   string
+	tokenKey    string
+	tokenURL    string
+	middlewares []Middleware
+	lockTTL     int64
+	source      TokenSource
+	cache       cache.Cache
+	cachePrefix string
+	mu          sync.Mutex
 }
 
 /*AccessTokenSafeSeconds token安全时间 */
@@ -36,6 +44,9 @@ func tokenURL(obj *AccessToken) string {
 	if obj != nil && obj.tokenURL != "" {
 		return obj.tokenURL
 	}
+	if obj != nil && obj.AccessTokenProperty != nil && obj.Stable {
+		return stableAccessToken
+	}
 	return accessToken
 }
 
@@ -57,6 +68,22 @@ func (obj *AccessToken) parse(options ...AccessTokenOption) {
 	}
 }
 
+// TokenSource return the source to fetch token,default is /cgi-bin/token
+func (obj *AccessToken) TokenSource() TokenSource {
+	if s, b := obj.source.(cachedTokenSource); b {
+		return s.bind(obj)
+	}
+	if obj.source != nil {
+		return obj.source
+	}
+	return &defaultTokenSource{AccessToken: obj}
+}
+
+// Cache return the cache of token,default is cache.DefaultCache()
+func (obj *AccessToken) Cache() cache.Cache {
+	return useCache(obj.cache)
+}
+
 /*Refresh 刷新AccessToken */
 func (obj *AccessToken) Refresh() *AccessToken {
 	log.Debug("GetToken|Refresh")
@@ -64,6 +91,27 @@ func (obj *AccessToken) Refresh() *AccessToken {
 	return obj
 }
 
+/*RefreshStale 仅当缓存中仍为失效token时刷新,并发调用只刷新一次 */
+func (obj *AccessToken) RefreshStale(stale string) *Token {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if token := obj.getToken(false); token != nil && token.AccessToken != stale {
+		return token
+	}
+	log.Debug("GetToken|RefreshStale")
+	//stable token is not forced,the token of the other services is still valid,
+	//but it is forced once when the same invalid token is returned
+	token, e := obj.loadToken(true, false)
+	if e == nil && token.AccessToken == stale {
+		token, e = obj.loadToken(true, true)
+	}
+	if e != nil {
+		log.Error(e)
+		return nil
+	}
+	return token
+}
+
 /*GetRefreshToken 获取刷新token */
 func (obj *AccessToken) GetRefreshToken() *Token {
 	log.Debug("GetToken|GetRefreshedToken")
@@ -81,39 +129,61 @@ func (obj *AccessToken) KeyMap() util.Map {
 }
 
 func (obj *AccessToken) getToken(refresh bool) *Token {
+	token, e := obj.loadToken(refresh, refresh)
+	if e != nil {
+		log.Error(e)
+		return nil
+	}
+	return token
+}
+
+// loadToken refresh: skip the cache,force: invalidate the old token when the source supported
+func (obj *AccessToken) loadToken(refresh, force bool) (*Token, error) {
 	key := obj.getCacheKey()
-	log.Infof("cached key:%t,%s", cache.Has(key), key)
-	if !refresh && cache.Has(key) {
-		if v, b := cache.Get(key).(string); b {
-			token, e := ParseToken(v)
-			if e != nil {
-				log.Error("parse token error")
-				return nil
-			}
-			log.Infof("cached accessToken:%+v", v)
-			return token
+	source := obj.TokenSource()
+	v, e := loadOrRefresh(obj.Cache(), key, refresh, obj.lockTTL, func() (string, int64, error) {
+		var token *Token
+		var e error
+		if fs, b := source.(ForceTokenSource); b && force {
+			token, e = fs.ForceToken()
+		} else {
+			token, e = source.Token()
+		}
+		if e != nil {
+			return "", 0, e
+		}
+		log.Infof("accessToken:%+v", *token)
+		if _, b := source.(cachedTokenSource); b {
+			return token.ToJSON(), 0, nil
 		}
+		return token.ToJSON(), tokenLife(token.ExpiresIn), nil
+	})
+	if e != nil {
+		return nil, e
 	}
-
-	token, e := requestToken(obj.TokenURL(), obj.AccessTokenProperty)
+	token, e := ParseToken(v)
 	if e != nil {
-		log.Error(e)
-		return nil
+		return nil, xerrors.Errorf("parse token error: %w", e)
 	}
+	return token, nil
+}
 
-	log.Infof("accessToken:%+v", *token)
-	if v := token.ExpiresIn; v != 0 {
-		obj.SetTokenWithLife(token.ToJSON(), v-AccessTokenSafeSeconds)
-	} else {
-		obj.SetToken(token.ToJSON())
+// tokenLife the cached life time of a token or ticket
+func tokenLife(expiresIn int64) int64 {
+	if expiresIn > AccessTokenSafeSeconds {
+		return expiresIn - AccessTokenSafeSeconds
 	}
-	return token
+	return 7200 - AccessTokenSafeSeconds
 }
 
-func requestToken(url string, credentials *AccessTokenProperty) (*Token, error) {
+func (obj *AccessToken) client(options ...ClientOption) *Client {
+	return NewClient(append([]ClientOption{ClientMiddleware(obj.middlewares...)}, options...)...)
+}
+
+func requestToken(client *Client, url string, credentials *AccessTokenProperty) (*Token, error) {
 	var t Token
 	var e error
-	token := Get(url, credentials.ToMap())
+	token := client.Get(context.Background(), url, credentials.ToMap())
 	if e := token.Error(); e != nil {
 		return nil, e
 	}
@@ -135,7 +205,7 @@ func (obj *AccessToken) SetToken(token string) *AccessToken {
 }
 
 func (obj *AccessToken) setToken(token string, lifeTime int64) *AccessToken {
-	cache.SetWithTTL(obj.getCacheKey(), token, lifeTime)
+	obj.Cache().SetWithTTL(obj.getCacheKey(), token, lifeTime)
 	return obj
 }
 
@@ -146,7 +216,7 @@ func (obj *AccessToken) getCredentials() string {
 }
 
 func (obj *AccessToken) getCacheKey() string {
-	return "godcong.wego.access_token." + obj.getCredentials()
+	return obj.cachePrefix + "godcong.wego.access_token." + obj.getCredentials()
 }
 
 const accessTokenNil = "nil point accessToken"
@@ -182,3 +252,18 @@ func parseAccessToken(token interface{}) string {
 	}
 	return ""
 }
+
+/*requestStableToken 获取稳定版接口调用凭据,force为true时强制刷新 */
+func requestStableToken(client *Client, url string, credentials *AccessTokenProperty, force bool) (*Token, error) {
+	var t Token
+	body := credentials.ToMap().Set("force_refresh", force)
+	body.Set("grant_type", util.MustString(credentials.GrantType, GrantTypeClient))
+	token := client.Post(context.Background(), url, nil, body)
+	if e := token.Error(); e != nil {
+		return nil, e
+	}
+	if e := token.Unmarshal(&t); e != nil {
+		return nil, e
+	}
+	return &t, nil
+}
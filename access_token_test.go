@@ -0,0 +1,150 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/godcong/wego/cache"
+	"github.com/godcong/wego/util"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// TestClient_TokenRefresh ...
+func TestClient_TokenRefresh(t *testing.T) {
+	var fetched int32
+	var current atomic.Value
+	current.Store("")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == accessToken {
+			token := "token" + strconv.Itoa(int(atomic.AddInt32(&fetched, 1)))
+			current.Store(token)
+			_, _ = w.Write([]byte(`{"access_token":"` + token + `","expires_in":7200}`))
+			return
+		}
+		if r.URL.Query().Get(accessTokenKey) != current.Load().(string) {
+			_, _ = w.Write([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+	}))
+	defer server.Close()
+
+	token := NewAccessToken(&AccessTokenProperty{AppID: "refresh", AppSecret: "secret"}, AccessTokenRemote(server.URL))
+	token.SetToken(`{"access_token":"stale","expires_in":7200}`)
+	client := NewClient(ClientAccessToken(token), ClientBodyType(BodyTypeJSON))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			resp := client.Post(context.Background(), server.URL+"/cgi-bin/api", nil, util.Map{})
+			if resp.Error() != nil {
+				t.Error(resp.Error())
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&fetched); n != 1 {
+		t.Errorf("token should be refreshed once,got %d", n)
+	}
+}
+
+// TestAccessToken_Stable ...
+func TestAccessToken_Stable(t *testing.T) {
+	var forced []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != stableAccessToken {
+			t.Errorf("wrong request:%s %s", r.Method, r.URL.Path)
+		}
+		m := util.Map{}
+		if e := jsoniter.NewDecoder(r.Body).Decode(&m); e != nil {
+			t.Error(e)
+			return
+		}
+		if m.GetString("grant_type") != GrantTypeClient || m.GetString("appid") != "stable" {
+			t.Errorf("wrong body:%+v", m)
+		}
+		forced = append(forced, m.GetBool("force_refresh"))
+		_, _ = w.Write([]byte(`{"access_token":"token` + strconv.Itoa(len(forced)) + `","expires_in":7200}`))
+	}))
+	defer server.Close()
+
+	token := NewAccessToken(&AccessTokenProperty{AppID: "stable", AppSecret: "secret", Stable: true}, AccessTokenRemote(server.URL),
+		AccessTokenCache(cache.NewMapCache()))
+	if v := token.GetToken(); v == nil || v.AccessToken != "token1" {
+		t.Fatalf("wrong token:%+v", v)
+	}
+	if v := token.GetRefreshToken(); v == nil || v.AccessToken != "token2" {
+		t.Fatalf("wrong token:%+v", v)
+	}
+	if _, e := token.loadToken(true, false); e != nil {
+		t.Fatal(e)
+	}
+	//the invalid token is reloaded without force
+	if v := token.RefreshStale("token3"); v == nil || v.AccessToken != "token4" {
+		t.Fatalf("wrong token:%+v", v)
+	}
+	if len(forced) != 4 || forced[0] || !forced[1] || forced[2] || forced[3] {
+		t.Errorf("wrong force_refresh:%v", forced)
+	}
+}
+
+// TestAccessToken_StableStale ...
+func TestAccessToken_StableStale(t *testing.T) {
+	var forced []bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m := util.Map{}
+		if e := jsoniter.NewDecoder(r.Body).Decode(&m); e != nil {
+			t.Error(e)
+			return
+		}
+		forced = append(forced, m.GetBool("force_refresh"))
+		//stable_token return the same token until it expires,unless forced
+		if m.GetBool("force_refresh") {
+			_, _ = w.Write([]byte(`{"access_token":"forced","expires_in":7200}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"stale","expires_in":7200}`))
+	}))
+	defer server.Close()
+
+	token := NewAccessToken(&AccessTokenProperty{AppID: "stable", AppSecret: "secret", Stable: true}, AccessTokenRemote(server.URL),
+		AccessTokenCache(cache.NewMapCache()))
+	token.SetToken(`{"access_token":"stale","expires_in":7200}`)
+	if v := token.RefreshStale("stale"); v == nil || v.AccessToken != "forced" {
+		t.Fatalf("wrong token:%+v", v)
+	}
+	if len(forced) != 2 || forced[0] || !forced[1] {
+		t.Errorf("the same stale token should be forced once:%v", forced)
+	}
+}
+
+// TestAccessToken_Cache ...
+func TestAccessToken_Cache(t *testing.T) {
+	c1, c2 := cache.NewMapCache(), cache.NewMapCache()
+	property := &AccessTokenProperty{AppID: "tenant", AppSecret: "secret"}
+	t1 := NewAccessToken(property, AccessTokenCache(c1), AccessTokenCachePrefix("prod:"))
+	t2 := NewAccessToken(property, AccessTokenCache(c2))
+	t1.SetToken(`{"access_token":"token1","expires_in":7200}`)
+	t2.SetToken(`{"access_token":"token2","expires_in":7200}`)
+
+	if v := t1.GetToken(); v == nil || v.AccessToken != "token1" {
+		t.Errorf("wrong token:%+v", v)
+	}
+	if v := t2.GetToken(); v == nil || v.AccessToken != "token2" {
+		t.Errorf("wrong token:%+v", v)
+	}
+	if !strings.HasPrefix(t1.getCacheKey(), "prod:") || !c1.Has(t1.getCacheKey()) || c1.Has(t2.getCacheKey()) {
+		t.Error("token should be cached with prefix in its own cache")
+	}
+	if cache.Has(t1.getCacheKey()) || cache.Has(t2.getCacheKey()) {
+		t.Error("default cache should not be used")
+	}
+}
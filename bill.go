@@ -0,0 +1,579 @@
+package wego
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/godcong/wego/util"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/xerrors"
+)
+
+// BillTimeFormat the time format of bill
+const BillTimeFormat = "2006-01-02 15:04:05"
+
+const billMaxLine = 1 << 20
+
+var billLocation = time.FixedZone("CST", 8*3600)
+
+// BillRecord a record of trade bill,the fields not in the bill type are empty.
+// the amounts are in fen(分)
+type BillRecord struct {
+	TradeTime          time.Time
+	AppID              string
+	MchID              string
+	SubMchID           string
+	DeviceInfo         string
+	TransactionID      string
+	OutTradeNo         string
+	OpenID             string
+	TradeType          string
+	TradeState         string
+	BankType           string
+	FeeType            string
+	SettlementTotalFee int64
+	CouponFee          int64
+	RefundApplyTime    time.Time
+	RefundSuccessTime  time.Time
+	RefundID           string
+	OutRefundNo        string
+	RefundFee          int64
+	CouponRefundFee    int64
+	RefundType         string
+	RefundStatus       string
+	Body               string
+	Attach             string
+	ServiceFee         int64
+	Rate               string
+	TotalFee           int64
+	RefundRequestFee   int64
+	RateNote           string
+	// Extra the columns not known
+	Extra map[string]string
+}
+
+// BillSummary the summary of trade bill
+type BillSummary struct {
+	TotalCount         int64
+	SettlementTotalFee int64
+	RefundFee          int64
+	CouponRefundFee    int64
+	ServiceFee         int64
+	TotalFee           int64
+	RefundRequestFee   int64
+}
+
+// FundFlowRecord a record of fund flow,the amounts are in fen(分)
+type FundFlowRecord struct {
+	Time         time.Time
+	BizTransID   string
+	FlowID       string
+	BizName      string
+	BizType      string
+	Type         string
+	Amount       int64
+	Balance      int64
+	Applicant    string
+	Remark       string
+	BizVoucherID string
+	Extra        map[string]string
+}
+
+// FundFlowSummary the summary of fund flow
+type FundFlowSummary struct {
+	TotalCount   int64
+	IncomeCount  int64
+	Income       int64
+	ExpenseCount int64
+	Expense      int64
+}
+
+type billField func(v string, target interface{}) error
+
+func billString(set func(r *BillRecord, v string)) billField {
+	return func(v string, target interface{}) error {
+		set(target.(*BillRecord), v)
+		return nil
+	}
+}
+
+func billFee(set func(r *BillRecord, v int64)) billField {
+	return func(v string, target interface{}) error {
+		fee, e := parseBillFee(v)
+		set(target.(*BillRecord), fee)
+		return e
+	}
+}
+
+func billTime(set func(r *BillRecord, v time.Time)) billField {
+	return func(v string, target interface{}) error {
+		t, e := parseBillTime(v)
+		set(target.(*BillRecord), t)
+		return e
+	}
+}
+
+var billFields = map[string]billField{
+	"交易时间":         billTime(func(r *BillRecord, v time.Time) { r.TradeTime = v }),
+	"公众账号ID":       billString(func(r *BillRecord, v string) { r.AppID = v }),
+	"商户号":          billString(func(r *BillRecord, v string) { r.MchID = v }),
+	"特约商户号":        billString(func(r *BillRecord, v string) { r.SubMchID = v }),
+	"子商户号":         billString(func(r *BillRecord, v string) { r.SubMchID = v }),
+	"设备号":          billString(func(r *BillRecord, v string) { r.DeviceInfo = v }),
+	"微信订单号":        billString(func(r *BillRecord, v string) { r.TransactionID = v }),
+	"商户订单号":        billString(func(r *BillRecord, v string) { r.OutTradeNo = v }),
+	"用户标识":         billString(func(r *BillRecord, v string) { r.OpenID = v }),
+	"交易类型":         billString(func(r *BillRecord, v string) { r.TradeType = v }),
+	"交易状态":         billString(func(r *BillRecord, v string) { r.TradeState = v }),
+	"付款银行":         billString(func(r *BillRecord, v string) { r.BankType = v }),
+	"货币种类":         billString(func(r *BillRecord, v string) { r.FeeType = v }),
+	"应结订单金额":       billFee(func(r *BillRecord, v int64) { r.SettlementTotalFee = v }),
+	"代金券金额":        billFee(func(r *BillRecord, v int64) { r.CouponFee = v }),
+	"代金券或立减优惠金额":   billFee(func(r *BillRecord, v int64) { r.CouponFee = v }),
+	"退款申请时间":       billTime(func(r *BillRecord, v time.Time) { r.RefundApplyTime = v }),
+	"退款成功时间":       billTime(func(r *BillRecord, v time.Time) { r.RefundSuccessTime = v }),
+	"微信退款单号":       billString(func(r *BillRecord, v string) { r.RefundID = v }),
+	"商户退款单号":       billString(func(r *BillRecord, v string) { r.OutRefundNo = v }),
+	"退款金额":         billFee(func(r *BillRecord, v int64) { r.RefundFee = v }),
+	"充值券退款金额":      billFee(func(r *BillRecord, v int64) { r.CouponRefundFee = v }),
+	"代金券或立减优惠退款金额": billFee(func(r *BillRecord, v int64) { r.CouponRefundFee = v }),
+	"退款类型":         billString(func(r *BillRecord, v string) { r.RefundType = v }),
+	"退款状态":         billString(func(r *BillRecord, v string) { r.RefundStatus = v }),
+	"商品名称":         billString(func(r *BillRecord, v string) { r.Body = v }),
+	"商户数据包":        billString(func(r *BillRecord, v string) { r.Attach = v }),
+	"手续费":          billFee(func(r *BillRecord, v int64) { r.ServiceFee = v }),
+	"费率":           billString(func(r *BillRecord, v string) { r.Rate = v }),
+	"订单金额":         billFee(func(r *BillRecord, v int64) { r.TotalFee = v }),
+	"申请退款金额":       billFee(func(r *BillRecord, v int64) { r.RefundRequestFee = v }),
+	"费率备注":         billString(func(r *BillRecord, v string) { r.RateNote = v }),
+}
+
+func billSummaryField(set func(s *BillSummary, v int64), count bool) billField {
+	return func(v string, target interface{}) error {
+		parse := parseBillFee
+		if count {
+			parse = parseBillCount
+		}
+		i, e := parse(v)
+		set(target.(*BillSummary), i)
+		return e
+	}
+}
+
+var billSummaryFields = map[string]billField{
+	"总交易单数":    billSummaryField(func(s *BillSummary, v int64) { s.TotalCount = v }, true),
+	"应结订单总金额":  billSummaryField(func(s *BillSummary, v int64) { s.SettlementTotalFee = v }, false),
+	"退款总金额":    billSummaryField(func(s *BillSummary, v int64) { s.RefundFee = v }, false),
+	"充值券退款总金额": billSummaryField(func(s *BillSummary, v int64) { s.CouponRefundFee = v }, false),
+	"手续费总金额":   billSummaryField(func(s *BillSummary, v int64) { s.ServiceFee = v }, false),
+	"订单总金额":    billSummaryField(func(s *BillSummary, v int64) { s.TotalFee = v }, false),
+	"申请退款总金额":  billSummaryField(func(s *BillSummary, v int64) { s.RefundRequestFee = v }, false),
+}
+
+func fundFlowString(set func(r *FundFlowRecord, v string)) billField {
+	return func(v string, target interface{}) error {
+		set(target.(*FundFlowRecord), v)
+		return nil
+	}
+}
+
+func fundFlowFee(set func(r *FundFlowRecord, v int64)) billField {
+	return func(v string, target interface{}) error {
+		fee, e := parseBillFee(v)
+		set(target.(*FundFlowRecord), fee)
+		return e
+	}
+}
+
+var fundFlowFields = map[string]billField{
+	"记账时间": func(v string, target interface{}) (e error) {
+		target.(*FundFlowRecord).Time, e = parseBillTime(v)
+		return
+	},
+	"微信支付业务单号":  fundFlowString(func(r *FundFlowRecord, v string) { r.BizTransID = v }),
+	"资金流水单号":    fundFlowString(func(r *FundFlowRecord, v string) { r.FlowID = v }),
+	"业务名称":      fundFlowString(func(r *FundFlowRecord, v string) { r.BizName = v }),
+	"业务类型":      fundFlowString(func(r *FundFlowRecord, v string) { r.BizType = v }),
+	"收支类型":      fundFlowString(func(r *FundFlowRecord, v string) { r.Type = v }),
+	"收支金额（元）":   fundFlowFee(func(r *FundFlowRecord, v int64) { r.Amount = v }),
+	"账户结余（元）":   fundFlowFee(func(r *FundFlowRecord, v int64) { r.Balance = v }),
+	"资金变更提交申请人": fundFlowString(func(r *FundFlowRecord, v string) { r.Applicant = v }),
+	"备注":        fundFlowString(func(r *FundFlowRecord, v string) { r.Remark = v }),
+	"业务凭证号":     fundFlowString(func(r *FundFlowRecord, v string) { r.BizVoucherID = v }),
+}
+
+func fundFlowSummaryField(set func(s *FundFlowSummary, v int64), count bool) billField {
+	return func(v string, target interface{}) error {
+		parse := parseBillFee
+		if count {
+			parse = parseBillCount
+		}
+		i, e := parse(v)
+		set(target.(*FundFlowSummary), i)
+		return e
+	}
+}
+
+var fundFlowSummaryFields = map[string]billField{
+	"资金流水总笔数": fundFlowSummaryField(func(s *FundFlowSummary, v int64) { s.TotalCount = v }, true),
+	"收入笔数":    fundFlowSummaryField(func(s *FundFlowSummary, v int64) { s.IncomeCount = v }, true),
+	"收入金额":    fundFlowSummaryField(func(s *FundFlowSummary, v int64) { s.Income = v }, false),
+	"支出笔数":    fundFlowSummaryField(func(s *FundFlowSummary, v int64) { s.ExpenseCount = v }, true),
+	"支出金额":    fundFlowSummaryField(func(s *FundFlowSummary, v int64) { s.Expense = v }, false),
+}
+
+// billScanner scan the lines of bill,the first line without ` is the header,
+// the lines start with ` are the records,the next line without ` is the header of summary
+type billScanner struct {
+	scanner       *bufio.Scanner
+	closers       []io.Closer
+	header        []string
+	summaryHeader []string
+	summary       []string
+	cells         []string
+	line          int
+	err           error
+}
+
+// newBillScanner the gzip is detected by the magic number
+func newBillScanner(r io.Reader) (*billScanner, error) {
+	s := &billScanner{}
+	reader := bufio.NewReader(r)
+	if magic, e := reader.Peek(2); e == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, e := gzip.NewReader(reader)
+		if e != nil {
+			return nil, e
+		}
+		s.closers = append(s.closers, gz)
+		r = gz
+	} else {
+		r = reader
+	}
+	s.scanner = bufio.NewScanner(r)
+	s.scanner.Buffer(make([]byte, 64*1024), billMaxLine)
+	return s, nil
+}
+
+// next move to the next record,the summary is read when it returns false
+func (s *billScanner) next() bool {
+	for s.err == nil && s.scanner.Scan() {
+		s.line++
+		line, e := billLine(s.scanner.Bytes())
+		if e != nil {
+			s.err = xerrors.Errorf("line %d: %w", s.line, e)
+			return false
+		}
+		if line == "" {
+			continue
+		}
+		if !strings.HasPrefix(line, "`") {
+			if s.header == nil {
+				s.header = strings.Split(line, ",")
+			} else {
+				s.summaryHeader = strings.Split(line, ",")
+			}
+			continue
+		}
+		cells := strings.Split(line[1:], ",`")
+		if s.summaryHeader != nil {
+			s.summary = cells
+			continue
+		}
+		if s.header == nil {
+			s.err = xerrors.Errorf("line %d: record before header", s.line)
+			return false
+		}
+		s.cells = cells
+		return true
+	}
+	if s.err == nil {
+		s.err = s.scanner.Err()
+	}
+	return false
+}
+
+// fill set the cells to target by the fields of header,the unknown columns are returned
+func (s *billScanner) fill(header, cells []string, fields map[string]billField, target interface{}) (map[string]string, error) {
+	var extra map[string]string
+	for i, name := range header {
+		if i >= len(cells) {
+			break
+		}
+		name = strings.TrimSpace(name)
+		v := strings.TrimSpace(cells[i])
+		field, b := fields[name]
+		if !b {
+			if extra == nil {
+				extra = make(map[string]string)
+			}
+			extra[name] = v
+			continue
+		}
+		if e := field(v, target); e != nil {
+			return nil, xerrors.Errorf("line %d,%s: %w", s.line, name, e)
+		}
+	}
+	return extra, nil
+}
+
+// close close the gzip reader and then the body of download
+func (s *billScanner) close() error {
+	var err error
+	for _, c := range s.closers {
+		if e := c.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// billLine the line is decoded from GBK when it is not utf8
+func billLine(b []byte) (string, error) {
+	b = bytes.TrimRight(b, "\r")
+	b = bytes.TrimPrefix(b, []byte("\xef\xbb\xbf"))
+	if utf8.Valid(b) {
+		return string(b), nil
+	}
+	d, e := simplifiedchinese.GBK.NewDecoder().Bytes(b)
+	if e != nil {
+		return "", e
+	}
+	return string(d), nil
+}
+
+// parseBillFee parse yuan to fen without float
+func parseBillFee(v string) (int64, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, nil
+	}
+	neg := strings.HasPrefix(v, "-")
+	v = strings.TrimPrefix(v, "-")
+	s := strings.SplitN(v, ".", 2)
+	yuan, e := strconv.ParseInt(s[0], 10, 64)
+	if e != nil {
+		return 0, e
+	}
+	var fen int64
+	if len(s) == 2 {
+		f := (s[1] + "00")[:2]
+		if len(s[1]) > 2 && strings.Trim(s[1][2:], "0") != "" {
+			return 0, xerrors.Errorf("amount %s is less than fen", v)
+		}
+		if fen, e = strconv.ParseInt(f, 10, 64); e != nil {
+			return 0, e
+		}
+	}
+	fee := yuan*100 + fen
+	if neg {
+		fee = -fee
+	}
+	return fee, nil
+}
+
+// parseBillCount the count may be 20.0
+func parseBillCount(v string) (int64, error) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, nil
+	}
+	f, e := strconv.ParseFloat(v, 64)
+	return int64(f), e
+}
+
+func parseBillTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(BillTimeFormat, v, billLocation)
+}
+
+// BillReader read the records of trade bill(ALL,SUCCESS and REFUND) one by one,
+// the bill can be gzipped(tar_type=GZIP) or GBK encoded.
+type BillReader struct {
+	s       *billScanner
+	record  *BillRecord
+	summary *BillSummary
+	err     error
+}
+
+// NewBillReader ...
+func NewBillReader(r io.Reader) (*BillReader, error) {
+	s, e := newBillScanner(r)
+	if e != nil {
+		return nil, e
+	}
+	return &BillReader{s: s}, nil
+}
+
+// Next move to the next record,check Err when it returns false
+func (r *BillReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.s.next() {
+		r.readSummary()
+		return false
+	}
+	record := &BillRecord{}
+	extra, e := r.s.fill(r.s.header, r.s.cells, billFields, record)
+	if e != nil {
+		r.err = e
+		return false
+	}
+	record.Extra = extra
+	r.record = record
+	return true
+}
+
+// Record the current record
+func (r *BillReader) Record() *BillRecord {
+	return r.record
+}
+
+// readSummary the error of summary is returned by Err
+func (r *BillReader) readSummary() {
+	if r.summary != nil || r.s.err != nil || r.s.summary == nil {
+		return
+	}
+	summary := &BillSummary{}
+	if _, e := r.s.fill(r.s.summaryHeader, r.s.summary, billSummaryFields, summary); e != nil {
+		r.err = xerrors.Errorf("summary: %w", e)
+		return
+	}
+	r.summary = summary
+}
+
+// Summary the summary is read after Next returns false,nil when the bill has no summary
+func (r *BillReader) Summary() *BillSummary {
+	return r.summary
+}
+
+// Err ...
+func (r *BillReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.s.err
+}
+
+// Close ...
+func (r *BillReader) Close() error {
+	return r.s.close()
+}
+
+// FundFlowReader read the records of fund flow one by one
+type FundFlowReader struct {
+	s       *billScanner
+	record  *FundFlowRecord
+	summary *FundFlowSummary
+	err     error
+}
+
+// NewFundFlowReader ...
+func NewFundFlowReader(r io.Reader) (*FundFlowReader, error) {
+	s, e := newBillScanner(r)
+	if e != nil {
+		return nil, e
+	}
+	return &FundFlowReader{s: s}, nil
+}
+
+// Next move to the next record,check Err when it returns false
+func (r *FundFlowReader) Next() bool {
+	if r.err != nil {
+		return false
+	}
+	if !r.s.next() {
+		r.readSummary()
+		return false
+	}
+	record := &FundFlowRecord{}
+	extra, e := r.s.fill(r.s.header, r.s.cells, fundFlowFields, record)
+	if e != nil {
+		r.err = e
+		return false
+	}
+	record.Extra = extra
+	r.record = record
+	return true
+}
+
+// Record the current record
+func (r *FundFlowReader) Record() *FundFlowRecord {
+	return r.record
+}
+
+// readSummary the error of summary is returned by Err
+func (r *FundFlowReader) readSummary() {
+	if r.summary != nil || r.s.err != nil || r.s.summary == nil {
+		return
+	}
+	summary := &FundFlowSummary{}
+	if _, e := r.s.fill(r.s.summaryHeader, r.s.summary, fundFlowSummaryFields, summary); e != nil {
+		r.err = xerrors.Errorf("summary: %w", e)
+		return
+	}
+	r.summary = summary
+}
+
+// Summary the summary is read after Next returns false,nil when the bill has no summary
+func (r *FundFlowReader) Summary() *FundFlowSummary {
+	return r.summary
+}
+
+// Err ...
+func (r *FundFlowReader) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return r.s.err
+}
+
+// Close ...
+func (r *FundFlowReader) Close() error {
+	return r.s.close()
+}
+
+// BillReader download the trade bill and read it from the connection,
+// the bill is not kept in memory,close the reader after read
+func (obj *Payment) BillReader(ctx context.Context, bd string, opts ...util.Map) (*BillReader, error) {
+	resp := obj.postStream(ctx, obj.Client(), payDownloadBill, obj.billDownload(bd, opts...))
+	s, e := newStreamBillScanner(resp)
+	if e != nil {
+		return nil, e
+	}
+	return &BillReader{s: s}, nil
+}
+
+// FundFlowReader download the fund flow and read it from the connection,close the reader after read
+func (obj *Payment) FundFlowReader(ctx context.Context, bd string, at string, opts ...util.Map) (*FundFlowReader, error) {
+	resp := obj.postStream(ctx, obj.SafeClient(), payDownloadFundFlow, obj.billDownloadFundFlow(bd, at, opts...))
+	s, e := newStreamBillScanner(resp)
+	if e != nil {
+		return nil, e
+	}
+	return &FundFlowReader{s: s}, nil
+}
+
+// newStreamBillScanner the response is closed with the scanner
+func newStreamBillScanner(resp Responder) (*billScanner, error) {
+	if e := resp.Error(); e != nil {
+		_ = resp.Close()
+		return nil, e
+	}
+	s, e := newBillScanner(resp)
+	if e != nil {
+		_ = resp.Close()
+		return nil, e
+	}
+	s.closers = append(s.closers, resp)
+	return s, nil
+}
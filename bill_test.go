@@ -0,0 +1,124 @@
+package wego
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+)
+
+const testBill = "交易时间,公众账号ID,商户号,特约商户号,设备号,微信订单号,商户订单号,用户标识,交易类型,交易状态,付款银行,货币种类,应结订单金额,代金券金额,微信退款单号,商户退款单号,退款金额,充值券退款金额,退款类型,退款状态,商品名称,商户数据包,手续费,费率,订单金额,申请退款金额,费率备注\r\n" +
+	"`2014-11-10 16:33:45,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1001690740201411100005734289,`1415640626,`085e9858e3ba5186aafcbaed1,`MICROPAY,`SUCCESS,`OTHERS,`CNY,`0.01,`0.0,`0,`0,`0,`0,`,`,`被扫支付测试,`订单额外描述,`0,`0.60%,`0.01,`0.00,`\r\n" +
+	"`2014-11-10 16:46:14,`wx2421b1c4370ec43b,`10000100,`0,`1000,`1002780740201411100005729794,`1415635270,`085e9858e90ca40c0b5aee463,`MICROPAY,`REFUND,`OTHERS,`CNY,`1.20,`0.0,`2001550740201411100000119897,`1415635270,`1.20,`0.0,`ORIGINAL,`SUCCESS,`被扫支付测试,`订单额外描述,`0,`0.60%,`1.20,`1.20,`\r\n" +
+	"总交易单数,应结订单总金额,退款总金额,充值券退款总金额,手续费总金额,订单总金额,申请退款总金额\r\n" +
+	"`2,`1.21,`1.20,`0.0,`0,`1.21,`1.20\r\n"
+
+const testFundFlow = "记账时间,微信支付业务单号,资金流水单号,业务名称,业务类型,收支类型,收支金额（元）,账户结余（元）,资金变更提交申请人,备注,业务凭证号\n" +
+	"`2018-02-01 04:21:23,`50000305742018020103387128253,`1900009231201802015884652186,`退款,`退款,`支出,`0.02,`0.17,`system,`缺货,`REF4200000068201801293084726067\n" +
+	"资金流水总笔数,收入笔数,收入金额,支出笔数,支出金额\n" +
+	"`1.0,`0.0,`0.00,`1.0,`0.02\n"
+
+// TestBillReader ...
+func TestBillReader(t *testing.T) {
+	gbk, e := simplifiedchinese.GBK.NewEncoder().String(testBill)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, _ = gz.Write([]byte(gbk))
+	_ = gz.Close()
+
+	reader, e := NewBillReader(&buf)
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer reader.Close()
+	var records []*BillRecord
+	for reader.Next() {
+		records = append(records, reader.Record())
+	}
+	if e := reader.Err(); e != nil {
+		t.Fatal(e)
+	}
+	if len(records) != 2 {
+		t.Fatalf("wrong records:%d", len(records))
+	}
+	r := records[1]
+	if r.TradeState != "REFUND" || r.RefundFee != 120 || r.Body != "被扫支付测试" || r.Rate != "0.60%" || r.TradeTime.Unix() != 1415609174 {
+		t.Errorf("wrong record:%+v", r)
+	}
+	if s := reader.Summary(); s == nil || s.TotalCount != 2 || s.TotalFee != 121 || s.RefundFee != 120 {
+		t.Errorf("wrong summary:%+v", s)
+	}
+
+	flow, e := NewFundFlowReader(strings.NewReader(testFundFlow))
+	if e != nil {
+		t.Fatal(e)
+	}
+	if !flow.Next() || flow.Record().Amount != 2 || flow.Record().Balance != 17 || flow.Record().Type != "支出" {
+		t.Fatal(flow.Record(), flow.Err())
+	}
+	if flow.Next() || flow.Err() != nil {
+		t.Fatal(flow.Err())
+	}
+	if s := flow.Summary(); s == nil || s.TotalCount != 1 || s.Expense != 2 {
+		t.Errorf("wrong summary:%+v", s)
+	}
+}
+
+// TestPayment_BillReader ...
+func TestPayment_BillReader(t *testing.T) {
+	lines := strings.SplitAfter(testBill, "\r\n")
+	read := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		//the records are read before the rest of bill is sent
+		_, _ = w.Write([]byte(strings.Join(lines[:2], "")))
+		w.(http.Flusher).Flush()
+		select {
+		case <-read:
+		case <-time.After(time.Second):
+			return
+		}
+		_, _ = w.Write([]byte(strings.Join(lines[2:], "")))
+	}))
+	defer server.Close()
+
+	payment := NewPayment(&PaymentProperty{AppID: "wx2421b1c4370ec43b", MchID: "10000100", Key: "192006250b4c09247ec02edce69f6a2d"},
+		PaymentRemote(server.URL))
+	reader, e := payment.BillReader(context.Background(), "20141110")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer reader.Close()
+	if !reader.Next() || reader.Record().OutTradeNo != "1415640626" {
+		t.Fatal(reader.Record(), reader.Err())
+	}
+	close(read)
+	count := 1
+	for reader.Next() {
+		count++
+	}
+	if reader.Err() != nil || count != 2 || reader.Summary() == nil || reader.Summary().TotalCount != 2 {
+		t.Fatal(count, reader.Summary(), reader.Err())
+	}
+}
+
+// TestBillReader_BadSummary ...
+func TestBillReader_BadSummary(t *testing.T) {
+	reader, e := NewBillReader(strings.NewReader(strings.Replace(testBill, "`2,`1.21", "`2,`1.2.1", 1)))
+	if e != nil {
+		t.Fatal(e)
+	}
+	for reader.Next() {
+	}
+	if reader.Err() == nil || reader.Summary() != nil {
+		t.Error("the bad summary should be an error", reader.Summary())
+	}
+}
@@ -1,5 +1,7 @@
 package cache
 
+import "time"
+
 /*Cache define an cache interface */
 type Cache interface {
 	Get(key string) interface{}
@@ -14,6 +16,12 @@ type Cache interface {
 	DeleteMultiple(keys ...string) Cache
 }
 
+/*Expirer cache which can tell the remaining life of a key */
+type Expirer interface {
+	// TTL ttl is 0 when the key never expire
+	TTL(key string) (ttl time.Duration, found bool)
+}
+
 //var cache sync.Map
 var cache Cache
 
@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"golang.org/x/xerrors"
+)
+
+// ErrNotFound the key was not found or expired
+var ErrNotFound = xerrors.New("cache: key not found")
+
+/*ContextCache cache with context and error,a failed backend is not a miss */
+type ContextCache interface {
+	// Get return ErrNotFound when the key was not found
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set ttl 0 means never expire
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	Delete(ctx context.Context, key string) error
+}
+
+// contexter cache which has a native ContextCache
+type contexter interface {
+	Context() ContextCache
+}
+
+// NewContextCache adapt a Cache to ContextCache,
+// the native one is used when the cache support it(RedisCache)
+func NewContextCache(c Cache) ContextCache {
+	if cc, b := c.(contexter); b {
+		return cc.Context()
+	}
+	return &cacheAdapter{cache: c}
+}
+
+// DefaultContextCache adapt the default cache to ContextCache
+func DefaultContextCache() ContextCache {
+	return NewContextCache(cache)
+}
+
+type cacheAdapter struct {
+	cache Cache
+}
+
+// Get ...
+func (a *cacheAdapter) Get(ctx context.Context, key string) ([]byte, error) {
+	if e := ctx.Err(); e != nil {
+		return nil, e
+	}
+	switch v := a.cache.Get(key).(type) {
+	case nil:
+		return nil, ErrNotFound
+	case []byte:
+		return v, nil
+	case string:
+		return []byte(v), nil
+	default:
+		return []byte(fmt.Sprint(v)), nil
+	}
+}
+
+// Set ...
+func (a *cacheAdapter) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if e := ctx.Err(); e != nil {
+		return e
+	}
+	a.cache.SetWithTTL(key, string(val), ttlSeconds(ttl))
+	return nil
+}
+
+// Delete ...
+func (a *cacheAdapter) Delete(ctx context.Context, key string) error {
+	if e := ctx.Err(); e != nil {
+		return e
+	}
+	a.cache.Delete(key)
+	return nil
+}
+
+// ttlSeconds convert ttl to seconds,a positive ttl less than one second is one second
+func ttlSeconds(ttl time.Duration) int64 {
+	if ttl <= 0 {
+		return 0
+	}
+	if ttl < time.Second {
+		return 1
+	}
+	return int64(ttl / time.Second)
+}
@@ -0,0 +1,36 @@
+package cache_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/godcong/wego/cache"
+)
+
+// TestNewContextCache ...
+func TestNewContextCache(t *testing.T) {
+	c := cache.NewContextCache(cache.NewMapCache())
+	ctx := context.Background()
+	if _, e := c.Get(ctx, "missing"); e != cache.ErrNotFound {
+		t.Errorf("want ErrNotFound,got %v", e)
+	}
+	if e := c.Set(ctx, "key", []byte("value"), time.Minute); e != nil {
+		t.Fatal(e)
+	}
+	if v, e := c.Get(ctx, "key"); e != nil || string(v) != "value" {
+		t.Error(string(v), e)
+	}
+	if e := c.Delete(ctx, "key"); e != nil {
+		t.Fatal(e)
+	}
+	if _, e := c.Get(ctx, "key"); e != cache.ErrNotFound {
+		t.Errorf("want ErrNotFound,got %v", e)
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, e := c.Get(canceled, "key"); e != context.Canceled {
+		t.Errorf("want context.Canceled,got %v", e)
+	}
+}
@@ -0,0 +1,264 @@
+package cache
+
+import (
+	"crypto/md5"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+)
+
+const fileCacheExt = ".cache"
+const fileCacheLockName = ".lock"
+
+// fileCacheKeyLockExt the key locks are not values,so Clear does not remove them
+const fileCacheKeyLockExt = ".keylock"
+
+// FileCache persist the values to a directory,one file for each key.
+// the files are replaced atomically and the writes are guarded by a file lock,
+// so the processes on the same host can share one directory.
+// the values are stored as json,use string values to get the same type back
+type FileCache struct {
+	dir string
+	mu  sync.Mutex
+}
+
+type fileCacheData struct {
+	Key    string      `json:"key"`
+	Value  interface{} `json:"value"`
+	Expire int64       `json:"expire"`
+}
+
+// NewFileCache dir is created when not exist
+func NewFileCache(dir string) (*FileCache, error) {
+	if e := os.MkdirAll(dir, 0700); e != nil {
+		return nil, e
+	}
+	return &FileCache{dir: dir}, nil
+}
+
+func (f *FileCache) path(key string) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%x", md5.Sum([]byte(key)))+fileCacheExt)
+}
+
+func (f *FileCache) lockPath(key string) string {
+	return filepath.Join(f.dir, fmt.Sprintf("%x", md5.Sum([]byte(key)))+fileCacheKeyLockExt)
+}
+
+// lock lock the directory in process and across processes
+func (f *FileCache) lock() (unlock func(), e error) {
+	f.mu.Lock()
+	file, e := os.OpenFile(filepath.Join(f.dir, fileCacheLockName), os.O_CREATE|os.O_RDWR, 0600)
+	if e != nil {
+		f.mu.Unlock()
+		return nil, e
+	}
+	if e := lockFile(file); e != nil {
+		_ = file.Close()
+		f.mu.Unlock()
+		return nil, e
+	}
+	return func() {
+		_ = unlockFile(file)
+		_ = file.Close()
+		f.mu.Unlock()
+	}, nil
+}
+
+// load read the data not expired,nil when not found
+func (f *FileCache) load(path string) *fileCacheData {
+	bytes, e := ioutil.ReadFile(path)
+	if e != nil {
+		return nil
+	}
+	var data fileCacheData
+	if e := jsoniter.Unmarshal(bytes, &data); e != nil {
+		log.Error(e)
+		return nil
+	}
+	if data.Expire > 0 && data.Expire < time.Now().Unix() {
+		return nil
+	}
+	return &data
+}
+
+// store write to a temp file then rename it,call it with the lock
+func (f *FileCache) store(path string, data *fileCacheData) error {
+	bytes, e := jsoniter.Marshal(data)
+	if e != nil {
+		return e
+	}
+	tmp, e := ioutil.TempFile(f.dir, "tmp-")
+	if e != nil {
+		return e
+	}
+	defer os.Remove(tmp.Name())
+	if _, e := tmp.Write(bytes); e != nil {
+		_ = tmp.Close()
+		return e
+	}
+	if e := tmp.Sync(); e != nil {
+		_ = tmp.Close()
+		return e
+	}
+	if e := tmp.Close(); e != nil {
+		return e
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+/*Get get value */
+func (f *FileCache) Get(key string) interface{} {
+	return f.GetD(key, nil)
+}
+
+/*GetD get value with default */
+func (f *FileCache) GetD(key string, v interface{}) interface{} {
+	if data := f.load(f.path(key)); data != nil {
+		return data.Value
+	}
+	return v
+}
+
+/*Set set value never expire */
+func (f *FileCache) Set(key string, val interface{}) Cache {
+	return f.SetWithTTL(key, val, 0)
+}
+
+/*SetWithTTL set value with ttl seconds */
+func (f *FileCache) SetWithTTL(key string, val interface{}, ttl int64) Cache {
+	data := &fileCacheData{
+		Key:   key,
+		Value: val,
+	}
+	if ttl > 0 {
+		data.Expire = time.Now().Unix() + ttl
+	}
+	unlock, e := f.lock()
+	if e != nil {
+		log.Error(e)
+		return f
+	}
+	defer unlock()
+	if e := f.store(f.path(key), data); e != nil {
+		log.Error(e)
+	}
+	return f
+}
+
+/*Has check exist */
+func (f *FileCache) Has(key string) bool {
+	return f.load(f.path(key)) != nil
+}
+
+/*TTL the remaining life of key */
+func (f *FileCache) TTL(key string) (ttl time.Duration, found bool) {
+	data := f.load(f.path(key))
+	if data == nil {
+		return 0, false
+	}
+	if data.Expire == 0 {
+		return 0, true
+	}
+	return time.Until(time.Unix(data.Expire, 0)), true
+}
+
+/*Delete delete value */
+func (f *FileCache) Delete(key string) Cache {
+	return f.DeleteMultiple(key)
+}
+
+/*Clear delete all values */
+func (f *FileCache) Clear() {
+	unlock, e := f.lock()
+	if e != nil {
+		log.Error(e)
+		return
+	}
+	defer unlock()
+	files, e := ioutil.ReadDir(f.dir)
+	if e != nil {
+		log.Error(e)
+		return
+	}
+	for _, file := range files {
+		if strings.HasSuffix(file.Name(), fileCacheExt) {
+			_ = os.Remove(filepath.Join(f.dir, file.Name()))
+		}
+	}
+}
+
+/*GetMultiple get multiple values */
+func (f *FileCache) GetMultiple(keys ...string) map[string]interface{} {
+	c := make(map[string]interface{})
+	for _, key := range keys {
+		if v := f.Get(key); v != nil {
+			c[key] = v
+		}
+	}
+	return c
+}
+
+/*SetMultiple set multiple values */
+func (f *FileCache) SetMultiple(values map[string]interface{}) Cache {
+	for k, v := range values {
+		f.Set(k, v)
+	}
+	return f
+}
+
+/*DeleteMultiple delete multiple values */
+func (f *FileCache) DeleteMultiple(keys ...string) Cache {
+	unlock, e := f.lock()
+	if e != nil {
+		log.Error(e)
+		return f
+	}
+	defer unlock()
+	for _, key := range keys {
+		if e := os.Remove(f.path(key)); e != nil && !os.IsNotExist(e) {
+			log.Error(e)
+		}
+	}
+	return f
+}
+
+/*Lock lock a key across processes,the lock file is taken over after ttl seconds */
+func (f *FileCache) Lock(key string, ttl int64) (unlock func(), locked bool) {
+	path := f.lockPath(key)
+	release, e := f.lock()
+	if e != nil {
+		log.Error(e)
+		return nil, false
+	}
+	defer release()
+	if data := f.load(path); data != nil {
+		return nil, false
+	}
+	owner := fmt.Sprintf("%d.%d", os.Getpid(), time.Now().UnixNano())
+	if e := f.store(path, &fileCacheData{
+		Key:    key,
+		Value:  owner,
+		Expire: time.Now().Unix() + ttl,
+	}); e != nil {
+		log.Error(e)
+		return nil, false
+	}
+	return func() {
+		release, e := f.lock()
+		if e != nil {
+			log.Error(e)
+			return
+		}
+		defer release()
+		//the lock may be expired and taken by others
+		if data := f.load(path); data != nil && data.Value == owner {
+			_ = os.Remove(path)
+		}
+	}, true
+}
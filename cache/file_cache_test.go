@@ -0,0 +1,55 @@
+package cache_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/godcong/wego/cache"
+)
+
+// TestFileCache ...
+func TestFileCache(t *testing.T) {
+	dir, e := ioutil.TempDir("", "wego-cache")
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer os.RemoveAll(dir)
+
+	c, e := cache.NewFileCache(dir)
+	if e != nil {
+		t.Fatal(e)
+	}
+	c.SetWithTTL("token", "value", 60)
+	c.SetWithTTL("expired", "value", -1)
+
+	//another process open the same directory
+	other, _ := cache.NewFileCache(dir)
+	if v := other.Get("token"); v != "value" {
+		t.Errorf("wrong value:%v", v)
+	}
+	unlock, locked := c.Lock("token.lock", 5)
+	if !locked {
+		t.Fatal("lock failed")
+	}
+	if _, locked := other.Lock("token.lock", 5); locked {
+		t.Error("lock should be held")
+	}
+	unlock()
+	if _, locked := other.Lock("token.lock", 5); !locked {
+		t.Error("lock should be released")
+	}
+
+	other.Delete("token")
+	if c.Has("token") {
+		t.Error("token should be deleted")
+	}
+	c.Set("forever", "value")
+	c.Clear()
+	if c.Has("forever") {
+		t.Error("values should be cleared")
+	}
+	if _, locked := c.Lock("token.lock", 5); locked {
+		t.Error("lock should survive clear")
+	}
+}
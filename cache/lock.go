@@ -0,0 +1,15 @@
+package cache
+
+/*Locker cache which can lock a key across goroutines or processes */
+type Locker interface {
+	// Lock lock the key with ttl seconds,return an unlock func when locked
+	Lock(key string, ttl int64) (unlock func(), locked bool)
+}
+
+//Lock lock key with the default cache,locked is always true when the cache is not a locker
+func Lock(key string, ttl int64) (unlock func(), locked bool) {
+	if l, b := cache.(Locker); b {
+		return l.Lock(key, ttl)
+	}
+	return func() {}, true
+}
@@ -0,0 +1,7 @@
+package cache
+
+import (
+	"github.com/godcong/go-trait"
+)
+
+var log = trait.ZapSugar()
@@ -1,23 +1,79 @@
 package cache
 
 import (
+	"container/list"
 	"sync"
 	"time"
 )
 
 /*MapCache MapCache */
 type MapCache struct {
-	cache sync.Map
+	mu        sync.Mutex
+	items     map[string]*list.Element
+	lru       *list.List
+	locks     map[string]time.Time
+	capacity  int
+	interval  time.Duration
+	stop      chan struct{}
+	closeOnce sync.Once
+	stats     Stats
 }
 
 type mapCacheData struct {
+	key   string
 	value interface{}
 	life  *time.Time
 }
 
+// Stats the counters of MapCache
+type Stats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	// Size the count of entries,the expired entries not swept are included
+	Size int
+}
+
+// MapCacheOption ...
+type MapCacheOption func(m *MapCache)
+
+// MapCacheCapacity the max count of entries,the least recently used one is evicted when full.
+// 0 means unbounded
+func MapCacheCapacity(capacity int) MapCacheOption {
+	return func(m *MapCache) {
+		m.capacity = capacity
+	}
+}
+
+// MapCacheJanitor sweep the expired entries every interval in background,
+// call Close to stop it
+func MapCacheJanitor(interval time.Duration) MapCacheOption {
+	return func(m *MapCache) {
+		m.interval = interval
+	}
+}
+
 // NewMapCache ...
-func NewMapCache() *MapCache {
-	return &MapCache{}
+func NewMapCache(options ...MapCacheOption) *MapCache {
+	m := &MapCache{}
+	for _, o := range options {
+		o(m)
+	}
+	m.init()
+	if m.interval > 0 {
+		m.stop = make(chan struct{})
+		go m.janitor(m.interval, m.stop)
+	}
+	return m
+}
+
+// init make the zero MapCache usable,call it with mu locked
+func (m *MapCache) init() {
+	if m.items == nil {
+		m.items = make(map[string]*list.Element)
+		m.lru = list.New()
+		m.locks = make(map[string]time.Time)
+	}
 }
 
 /*Get check exist */
@@ -32,52 +88,96 @@ func (m *MapCache) Set(key string, val interface{}) Cache {
 
 /*GetD get interface with default */
 func (m *MapCache) GetD(key string, v0 interface{}) interface{} {
-	if v, b := m.cache.Load(key); b {
-		switch vv := v.(type) {
-		case *mapCacheData:
-			if vv.life != nil && vv.life.Before(time.Now()) {
-				m.cache.Delete(key)
-				return nil
-			}
-			return vv.value
-		}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if data := m.load(key, time.Now()); data != nil {
+		m.stats.Hits++
+		m.lru.MoveToFront(m.items[key])
+		return data.value
 	}
-	return nil
+	m.stats.Misses++
+	return v0
+}
+
+// load get the data not expired,the expired one is removed,call it with mu locked
+func (m *MapCache) load(key string, now time.Time) *mapCacheData {
+	m.init()
+	e, b := m.items[key]
+	if !b {
+		return nil
+	}
+	data := e.Value.(*mapCacheData)
+	if data.life != nil && data.life.Before(now) {
+		m.remove(e)
+		return nil
+	}
+	return data
 }
 
 /*SetWithTTL set interface with ttl */
 func (m *MapCache) SetWithTTL(key string, val interface{}, ttl int64) Cache {
-	t := time.Now().Add(time.Duration(ttl))
-	m.cache.Store(key, &mapCacheData{
+	return m.setWithDuration(key, val, time.Duration(ttl)*time.Second)
+}
+
+// setWithDuration ttl less than or equal to 0 means never expire
+func (m *MapCache) setWithDuration(key string, val interface{}, ttl time.Duration) Cache {
+	var life *time.Time
+	if ttl > 0 {
+		t := time.Now().Add(ttl)
+		life = &t
+	}
+	data := &mapCacheData{
+		key:   key,
 		value: val,
-		life:  &t,
-	})
+		life:  life,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if e, b := m.items[key]; b {
+		e.Value = data
+		m.lru.MoveToFront(e)
+		return m
+	}
+	m.items[key] = m.lru.PushFront(data)
+	for m.capacity > 0 && m.lru.Len() > m.capacity {
+		m.remove(m.lru.Back())
+		m.stats.Evictions++
+	}
 	return m
 }
 
+// remove call it with mu locked
+func (m *MapCache) remove(e *list.Element) {
+	m.lru.Remove(e)
+	delete(m.items, e.Value.(*mapCacheData).key)
+}
+
 /*Has check exist */
 func (m *MapCache) Has(key string) bool {
-	if v, b := m.cache.Load(key); b {
-		switch vv := v.(type) {
-		case *mapCacheData:
-			if vv.life != nil && vv.life.Before(time.Now()) {
-				return false
-			}
-			return true
-		}
-	}
-	return false
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.load(key, time.Now()) != nil
 }
 
 /*Delete one value */
 func (m *MapCache) Delete(key string) Cache {
-	m.Delete(key)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if e, b := m.items[key]; b {
+		m.remove(e)
+	}
 	return m
 }
 
 /*Clear delete all values */
 func (m *MapCache) Clear() {
-	*m = MapCache{}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items = nil
+	m.init()
 }
 
 /*GetMultiple get multiple values */
@@ -111,3 +211,91 @@ func (m *MapCache) DeleteMultiple(keys ...string) Cache {
 
 	return m
 }
+
+/*Lock lock a key in process,the locks are not counted in capacity */
+func (m *MapCache) Lock(key string, ttl int64) (unlock func(), locked bool) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	if life, b := m.locks[key]; b && life.After(now) {
+		return nil, false
+	}
+	life := now.Add(time.Duration(ttl) * time.Second)
+	m.locks[key] = life
+	return func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		//the lock may be expired and taken by others
+		if m.locks[key].Equal(life) {
+			delete(m.locks, key)
+		}
+	}, true
+}
+
+/*TTL the remaining life of key */
+func (m *MapCache) TTL(key string) (ttl time.Duration, found bool) {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data := m.load(key, now)
+	if data == nil {
+		return 0, false
+	}
+	if data.life == nil {
+		return 0, true
+	}
+	return data.life.Sub(now), true
+}
+
+/*Stats return the counters */
+func (m *MapCache) Stats() Stats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats := m.stats
+	stats.Size = len(m.items)
+	return stats
+}
+
+/*Close stop the janitor */
+func (m *MapCache) Close() error {
+	m.closeOnce.Do(func() {
+		if m.stop != nil {
+			close(m.stop)
+		}
+	})
+	return nil
+}
+
+func (m *MapCache) janitor(interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			m.sweep()
+		}
+	}
+}
+
+// sweep remove all expired entries and locks
+func (m *MapCache) sweep() {
+	now := time.Now()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	for e := m.lru.Back(); e != nil; {
+		prev := e.Prev()
+		if data := e.Value.(*mapCacheData); data.life != nil && data.life.Before(now) {
+			m.remove(e)
+		}
+		e = prev
+	}
+	for key, life := range m.locks {
+		if life.Before(now) {
+			delete(m.locks, key)
+		}
+	}
+}
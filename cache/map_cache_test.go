@@ -18,3 +18,32 @@ func TestMapCache_SetWithTTL(t *testing.T) {
 	log.Println(c.Get("hello"))
 	log.Println(c.Get("hello1"))
 }
+
+// TestMapCache_Capacity ...
+func TestMapCache_Capacity(t *testing.T) {
+	c := cache.NewMapCache(cache.MapCacheCapacity(2))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+	if c.Has("b") || !c.Has("a") || !c.Has("c") {
+		t.Error("the least recently used one should be evicted")
+	}
+	c.Get("b")
+	stats := c.Stats()
+	if stats.Hits != 1 || stats.Misses != 1 || stats.Evictions != 1 || stats.Size != 2 {
+		t.Errorf("wrong stats:%+v", stats)
+	}
+}
+
+// TestMapCache_Janitor ...
+func TestMapCache_Janitor(t *testing.T) {
+	c := cache.NewMapCache(cache.MapCacheJanitor(100 * time.Millisecond))
+	defer c.Close()
+	c.SetWithTTL("expired", "value", 1)
+	c.Set("forever", "value")
+	time.Sleep(1300 * time.Millisecond)
+	if size := c.Stats().Size; size != 1 {
+		t.Errorf("expired entry should be swept,size:%d", size)
+	}
+}
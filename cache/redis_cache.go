@@ -1,13 +1,35 @@
 package cache
 
 import (
+	"context"
 	"github.com/go-redis/redis"
+	"github.com/godcong/wego/util"
+	"golang.org/x/xerrors"
+	"strings"
 	"time"
 )
 
+// defaultRedisPrefix the prefix of keys written by wego
+const defaultRedisPrefix = "godcong.wego."
+
+// redisScanCount the count hint of SCAN
+const redisScanCount = 500
+
 // RedisCache ...
 type RedisCache struct {
-	client *redis.Client
+	client redis.UniversalClient
+	prefix string
+}
+
+// RedisCacheOption ...
+type RedisCacheOption func(r *RedisCache)
+
+// RedisCachePrefix Clear only delete the keys with prefix,
+// default is the keys contain godcong.wego.,which may be prepended by the cache prefix of AccessToken,JSSDK or Payment
+func RedisCachePrefix(prefix string) RedisCacheOption {
+	return func(r *RedisCache) {
+		r.prefix = prefix
+	}
 }
 
 // Get ...
@@ -45,60 +67,262 @@ func (r *RedisCache) Has(key string) bool {
 	return true
 }
 
+// TTL ...
+func (r *RedisCache) TTL(key string) (ttl time.Duration, found bool) {
+	d, e := r.client.PTTL(key).Result()
+	if e != nil || d == -2*time.Millisecond {
+		return 0, false
+	}
+	if d < 0 {
+		return 0, true
+	}
+	return d, true
+}
+
 // Delete ...
 func (r *RedisCache) Delete(key string) Cache {
 	r.client.Del(key)
 	return r
 }
 
-// Clear ...
+// Clear delete the keys matched by prefix,on every master of cluster
 func (r *RedisCache) Clear() {
-	r.client.FlushDB()
+	if cluster, b := r.client.(*redis.ClusterClient); b {
+		e := cluster.ForEachMaster(func(client *redis.Client) error {
+			return r.clear(client)
+		})
+		if e != nil {
+			log.Error(e)
+		}
+		return
+	}
+	if e := r.clear(r.client); e != nil {
+		log.Error(e)
+	}
+}
+
+// match the keys of wego are written as cache prefix + godcong.wego.
+func (r *RedisCache) match() string {
+	if r.prefix == defaultRedisPrefix {
+		return "*" + escapeRedisPattern(r.prefix) + "*"
+	}
+	return escapeRedisPattern(r.prefix) + "*"
 }
 
-// GetMultiple ...
+func (r *RedisCache) clear(client redis.Cmdable) error {
+	match := r.match()
+	var cursor uint64
+	for {
+		keys, next, e := client.Scan(cursor, match, redisScanCount).Result()
+		if e != nil {
+			return e
+		}
+		if len(keys) > 0 {
+			if e := r.del(client, keys...); e != nil {
+				return e
+			}
+		}
+		if next == 0 {
+			return nil
+		}
+		cursor = next
+	}
+}
+
+// del delete the keys one by one in pipeline,the keys may be in different slots of cluster
+func (r *RedisCache) del(client redis.Cmdable, keys ...string) error {
+	_, e := client.Pipelined(func(pipe redis.Pipeliner) error {
+		for _, key := range keys {
+			pipe.Del(key)
+		}
+		return nil
+	})
+	return e
+}
+
+// escapeRedisPattern escape the special characters of SCAN MATCH
+func escapeRedisPattern(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "*", `\*`, "?", `\?`, "[", `\[`, "]", `\]`).Replace(s)
+}
+
+// GetMultiple get the values in pipeline,the value is nil when not found
 func (r *RedisCache) GetMultiple(keys ...string) map[string]interface{} {
 	if keys == nil {
 		return nil
 	}
-	val := r.client.MGet(keys...).Val()
-	size := len(keys)
-	result := make(map[string]interface{}, size)
-	for i := 0; i < size; i++ {
-		result[keys[i]] = val[i]
+	cmds := make([]*redis.StringCmd, len(keys))
+	_, e := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			cmds[i] = pipe.Get(key)
+		}
+		return nil
+	})
+	if e != nil && e != redis.Nil {
+		log.Error(e)
+	}
+	result := make(map[string]interface{}, len(keys))
+	for i, key := range keys {
+		if v, e := cmds[i].Result(); e == nil {
+			result[key] = v
+			continue
+		}
+		result[key] = nil
 	}
 	return result
 }
 
-// SetMultiple ...
+// SetMultiple set the values in pipeline
 func (r *RedisCache) SetMultiple(values map[string]interface{}) Cache {
 	if values == nil {
-		return cache
+		return r
 	}
-
-	for key, value := range values {
-		r.client.Set(key, value, 0)
+	_, e := r.client.Pipelined(func(pipe redis.Pipeliner) error {
+		for key, value := range values {
+			pipe.Set(key, value, 0)
+		}
+		return nil
+	})
+	if e != nil {
+		log.Error(e)
 	}
-	return cache
+	return r
 }
 
 // DeleteMultiple ...
 func (r *RedisCache) DeleteMultiple(keys ...string) Cache {
-	r.client.Del(keys...)
+	if e := r.del(r.client, keys...); e != nil {
+		log.Error(e)
+	}
 	return r
 }
 
+var unlockScript = redis.NewScript(`if redis.call("get", KEYS[1]) == ARGV[1] then return redis.call("del", KEYS[1]) else return 0 end`)
+
+// Lock lock a key with SETNX,only the owner can unlock it
+func (r *RedisCache) Lock(key string, ttl int64) (unlock func(), locked bool) {
+	value := util.GenerateRandomString(16)
+	ok, e := r.client.SetNX(key, value, time.Duration(ttl)*time.Second).Result()
+	if e != nil || !ok {
+		return nil, false
+	}
+	return func() {
+		unlockScript.Run(r.client, []string{key}, value)
+	}, true
+}
+
+// Context return the ContextCache of redis,the errors of redis are returned
+func (r *RedisCache) Context() ContextCache {
+	return &redisContextCache{client: r.client}
+}
+
+type redisContextCache struct {
+	client redis.UniversalClient
+}
+
+// with the client with ctx,only Client and ClusterClient support context
+func (r *redisContextCache) with(ctx context.Context) redis.Cmdable {
+	switch c := r.client.(type) {
+	case *redis.Client:
+		return c.WithContext(ctx)
+	case *redis.ClusterClient:
+		return c.WithContext(ctx)
+	}
+	return r.client
+}
+
+// Get ...
+func (r *redisContextCache) Get(ctx context.Context, key string) ([]byte, error) {
+	b, e := r.with(ctx).Get(key).Bytes()
+	if e == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if e != nil {
+		return nil, xerrors.Errorf("redis get %s: %w", key, e)
+	}
+	return b, nil
+}
+
+// Set ...
+func (r *redisContextCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if e := r.with(ctx).Set(key, val, ttl).Err(); e != nil {
+		return xerrors.Errorf("redis set %s: %w", key, e)
+	}
+	return nil
+}
+
+// Delete ...
+func (r *redisContextCache) Delete(ctx context.Context, key string) error {
+	if e := r.with(ctx).Del(key).Err(); e != nil {
+		return xerrors.Errorf("redis delete %s: %w", key, e)
+	}
+	return nil
+}
+
+// Invalidator publish the invalidation of TieredCache with the redis channel
+func (r *RedisCache) Invalidator(channel string) Invalidator {
+	return &redisInvalidator{client: r.client, channel: channel}
+}
+
+type redisInvalidator struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+// Publish ...
+func (r *redisInvalidator) Publish(msg string) error {
+	return r.client.Publish(r.channel, msg).Err()
+}
+
+// Subscribe ...
+func (r *redisInvalidator) Subscribe(fn func(msg string)) (stop func(), e error) {
+	pubsub := r.client.Subscribe(r.channel)
+	if _, e := pubsub.Receive(); e != nil {
+		_ = pubsub.Close()
+		return nil, e
+	}
+	ch := pubsub.Channel()
+	go func() {
+		for msg := range ch {
+			fn(msg.Payload)
+		}
+	}()
+	return func() {
+		_ = pubsub.Close()
+	}, nil
+}
+
 // Options ...
 type Options struct {
 	Addr string
 }
 
 // NewRedisCache ...
-func NewRedisCache(op *redis.Options) *RedisCache {
-	client := redis.NewClient(op)
+func NewRedisCache(op *redis.Options, options ...RedisCacheOption) *RedisCache {
+	return NewRedisCacheWithClient(redis.NewClient(op), options...)
+}
+
+// NewRedisFailoverCache connect to redis with sentinel
+func NewRedisFailoverCache(op *redis.FailoverOptions, options ...RedisCacheOption) *RedisCache {
+	return NewRedisCacheWithClient(redis.NewFailoverClient(op), options...)
+}
+
+// NewRedisClusterCache connect to redis cluster
+func NewRedisClusterCache(op *redis.ClusterOptions, options ...RedisCacheOption) *RedisCache {
+	return NewRedisCacheWithClient(redis.NewClusterClient(op), options...)
+}
+
+// NewRedisCacheWithClient panic when the redis can not be connected
+func NewRedisCacheWithClient(client redis.UniversalClient, options ...RedisCacheOption) *RedisCache {
 	_, e := client.Ping().Result()
 	if e != nil {
 		panic(e)
 	}
-	return &RedisCache{client: client}
+	r := &RedisCache{
+		client: client,
+		prefix: defaultRedisPrefix,
+	}
+	for _, o := range options {
+		o(r)
+	}
+	return r
 }
@@ -1,22 +1,92 @@
 package cache
 
 import (
-	"github.com/go-redis/redis"
-	"github.com/godcong/wego/util"
+	"net"
 	"strconv"
+	"strings"
 	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/alicebob/miniredis/v2/server"
+	"github.com/go-redis/redis"
 )
 
+// testRedisClear Clear should only delete the keys of wego,by SCAN and pipelined DEL
+func testRedisClear(t *testing.T, r *RedisCache, mr *miniredis.Miniredis) {
+	mr.FlushAll()
+	values := make(map[string]interface{})
+	for i := 0; i < 100; i++ {
+		values["godcong.wego.token."+strconv.Itoa(i)] = "value"
+		values["prod:godcong.wego.token."+strconv.Itoa(i)] = "value"
+	}
+	r.SetMultiple(values)
+	r.Set("other.service.token", "foreign")
+	if m := r.GetMultiple("godcong.wego.token.1", "prod:godcong.wego.token.99"); len(m) != 2 {
+		t.Errorf("wrong values:%v", m)
+	}
+
+	r.Clear()
+	if keys := mr.Keys(); len(keys) != 1 || keys[0] != "other.service.token" {
+		t.Errorf("only the foreign key should survive:%v", keys)
+	}
+}
+
 // TestRedisCache_Clear ...
 func TestRedisCache_Clear(t *testing.T) {
-	rds := NewRedisCache(&redis.Options{
-		Addr:     "localhost:6379",
-		Password: "2rXfzaNKqX1b",
-		DB:       1,
+	mr, e := miniredis.Run()
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer mr.Close()
+
+	testRedisClear(t, NewRedisCache(&redis.Options{Addr: mr.Addr()}), mr)
+	testRedisClear(t, NewRedisClusterCache(&redis.ClusterOptions{Addrs: []string{mr.Addr()}}), mr)
+
+	//the sentinel answers the address of master
+	sentinel, e := miniredis.Run()
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer sentinel.Close()
+	host, port, _ := net.SplitHostPort(mr.Addr())
+	_ = sentinel.Server().Register("SENTINEL", func(c *server.Peer, cmd string, args []string) {
+		if len(args) > 0 && strings.EqualFold(args[0], "get-master-addr-by-name") {
+			c.WriteLen(2)
+			c.WriteBulk(host)
+			c.WriteBulk(port)
+			return
+		}
+		c.WriteLen(0)
 	})
-	RegisterCache(rds)
-	for i := 0; i < 100; i++ {
-		cache.Set(strconv.Itoa(i), util.GenerateRandomString(32))
+	testRedisClear(t, NewRedisFailoverCache(&redis.FailoverOptions{
+		MasterName:    "master",
+		SentinelAddrs: []string{sentinel.Addr()},
+	}), mr)
+
+	r := NewRedisCache(&redis.Options{Addr: mr.Addr()}, RedisCachePrefix("prod:godcong.wego."))
+	r.Set("godcong.wego.token", "value")
+	r.Set("prod:godcong.wego.token", "value")
+	r.Clear()
+	if !mr.Exists("godcong.wego.token") || mr.Exists("prod:godcong.wego.token") {
+		t.Errorf("only the keys with prefix should be deleted:%v", mr.Keys())
 	}
+}
 
+// TestEscapeRedisPattern ...
+func TestEscapeRedisPattern(t *testing.T) {
+	if s := escapeRedisPattern(`prod[1]*?\.`); s != `prod\[1\]\*\?\\.` {
+		t.Error(s)
+	}
+}
+
+// TestRedisCache_Match ...
+func TestRedisCache_Match(t *testing.T) {
+	r := &RedisCache{prefix: defaultRedisPrefix}
+	if m := r.match(); m != `*godcong.wego.*` {
+		t.Error("default should match the prefixed keys", m)
+	}
+	r.prefix = "prod:godcong.wego."
+	if m := r.match(); m != `prod:godcong.wego.*` {
+		t.Error(m)
+	}
 }
@@ -0,0 +1,286 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/godcong/wego/util"
+)
+
+const defaultTieredL1TTL = 5 * time.Second
+const defaultTieredL1Capacity = 1024
+const tieredInvalidateAll = ""
+
+/*Invalidator broadcast the changed keys to the other nodes */
+type Invalidator interface {
+	Publish(msg string) error
+	// Subscribe fn is called in another goroutine,call stop to unsubscribe
+	Subscribe(fn func(msg string)) (stop func(), e error)
+}
+
+// TieredCache serve the hot keys from an in-memory L1,and fall through to the L2(Redis).
+// the life of L1 never outlive the L2 expiry when L2 is an Expirer
+type TieredCache struct {
+	id          string
+	l1          *MapCache
+	l2          Cache
+	l1TTL       time.Duration
+	l1Capacity  int
+	invalidator Invalidator
+	unsubscribe func()
+}
+
+// TieredCacheOption ...
+type TieredCacheOption func(t *TieredCache)
+
+// TieredCacheL1TTL the max life of L1,default is 5s,L1 is disabled when it is not positive
+func TieredCacheL1TTL(ttl time.Duration) TieredCacheOption {
+	return func(t *TieredCache) {
+		t.l1TTL = ttl
+	}
+}
+
+// TieredCacheL1Capacity the max count of L1 entries,default is 1024
+func TieredCacheL1Capacity(capacity int) TieredCacheOption {
+	return func(t *TieredCache) {
+		t.l1Capacity = capacity
+	}
+}
+
+// TieredCacheInvalidator evict the L1 of the other nodes when a key was changed
+func TieredCacheInvalidator(invalidator Invalidator) TieredCacheOption {
+	return func(t *TieredCache) {
+		t.invalidator = invalidator
+	}
+}
+
+// NewTieredCache ...
+func NewTieredCache(l2 Cache, options ...TieredCacheOption) (*TieredCache, error) {
+	t := &TieredCache{
+		id:         util.GenerateRandomString(16),
+		l2:         l2,
+		l1TTL:      defaultTieredL1TTL,
+		l1Capacity: defaultTieredL1Capacity,
+	}
+	for _, o := range options {
+		o(t)
+	}
+	t.l1 = NewMapCache(MapCacheCapacity(t.l1Capacity), MapCacheJanitor(t.l1TTL))
+	if t.invalidator != nil {
+		stop, e := t.invalidator.Subscribe(t.invalidated)
+		if e != nil {
+			_ = t.l1.Close()
+			return nil, e
+		}
+		t.unsubscribe = stop
+	}
+	return t, nil
+}
+
+// invalidated the message is id|key
+func (t *TieredCache) invalidated(msg string) {
+	s := strings.SplitN(msg, "|", 2)
+	if len(s) != 2 || s[0] == t.id {
+		return
+	}
+	if s[1] == tieredInvalidateAll {
+		t.l1.Clear()
+		return
+	}
+	t.l1.Delete(s[1])
+}
+
+func (t *TieredCache) publish(keys ...string) {
+	if t.invalidator == nil {
+		return
+	}
+	for _, key := range keys {
+		if e := t.invalidator.Publish(t.id + "|" + key); e != nil {
+			log.Error(e)
+		}
+	}
+}
+
+// fill put the value of L2 to L1
+func (t *TieredCache) fill(key string, val interface{}) {
+	var ttl time.Duration
+	if e, b := t.l2.(Expirer); b {
+		var found bool
+		ttl, found = e.TTL(key)
+		if !found {
+			return
+		}
+	}
+	t.setL1(key, val, ttl)
+}
+
+// setL1 the L1 entry never outlive l1TTL and the L2 ttl,it is skipped when l1TTL is not positive
+func (t *TieredCache) setL1(key string, val interface{}, ttl time.Duration) {
+	life := t.l1TTL
+	if ttl > 0 && ttl < life {
+		life = ttl
+	}
+	if life <= 0 {
+		t.l1.Delete(key)
+		return
+	}
+	t.l1.setWithDuration(key, val, life)
+}
+
+/*Get get value */
+func (t *TieredCache) Get(key string) interface{} {
+	return t.GetD(key, nil)
+}
+
+/*GetD get value with default */
+func (t *TieredCache) GetD(key string, v interface{}) interface{} {
+	if val := t.l1.Get(key); val != nil {
+		return val
+	}
+	val := t.l2.Get(key)
+	if val == nil {
+		return v
+	}
+	t.fill(key, val)
+	return val
+}
+
+/*Set set value */
+func (t *TieredCache) Set(key string, val interface{}) Cache {
+	return t.SetWithTTL(key, val, 0)
+}
+
+/*SetWithTTL set value with ttl seconds */
+func (t *TieredCache) SetWithTTL(key string, val interface{}, ttl int64) Cache {
+	t.l2.SetWithTTL(key, val, ttl)
+	t.setL1(key, val, time.Duration(ttl)*time.Second)
+	t.publish(key)
+	return t
+}
+
+/*Has check exist */
+func (t *TieredCache) Has(key string) bool {
+	return t.l1.Has(key) || t.l2.Has(key)
+}
+
+/*Delete delete value */
+func (t *TieredCache) Delete(key string) Cache {
+	return t.DeleteMultiple(key)
+}
+
+/*Clear clear all */
+func (t *TieredCache) Clear() {
+	t.l2.Clear()
+	t.l1.Clear()
+	t.publish(tieredInvalidateAll)
+}
+
+/*GetMultiple get multiple values */
+func (t *TieredCache) GetMultiple(keys ...string) map[string]interface{} {
+	c := make(map[string]interface{})
+	var missed []string
+	for _, key := range keys {
+		if v := t.l1.Get(key); v != nil {
+			c[key] = v
+			continue
+		}
+		missed = append(missed, key)
+	}
+	if missed != nil {
+		for k, v := range t.l2.GetMultiple(missed...) {
+			c[k] = v
+		}
+	}
+	return c
+}
+
+/*SetMultiple set multiple values */
+func (t *TieredCache) SetMultiple(values map[string]interface{}) Cache {
+	t.l2.SetMultiple(values)
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		t.l1.Delete(k)
+		keys = append(keys, k)
+	}
+	t.publish(keys...)
+	return t
+}
+
+/*DeleteMultiple delete multiple values */
+func (t *TieredCache) DeleteMultiple(keys ...string) Cache {
+	t.l2.DeleteMultiple(keys...)
+	t.l1.DeleteMultiple(keys...)
+	t.publish(keys...)
+	return t
+}
+
+/*TTL the remaining life in L2 */
+func (t *TieredCache) TTL(key string) (ttl time.Duration, found bool) {
+	if e, b := t.l2.(Expirer); b {
+		return e.TTL(key)
+	}
+	return t.l1.TTL(key)
+}
+
+/*Lock lock with L2 when it is a Locker,otherwise lock in process */
+func (t *TieredCache) Lock(key string, ttl int64) (unlock func(), locked bool) {
+	if l, b := t.l2.(Locker); b {
+		return l.Lock(key, ttl)
+	}
+	return t.l1.Lock(key, ttl)
+}
+
+/*Close unsubscribe the invalidation and stop the janitor of L1 */
+func (t *TieredCache) Close() error {
+	if t.unsubscribe != nil {
+		t.unsubscribe()
+	}
+	return t.l1.Close()
+}
+
+/*Context the errors of L2 are returned */
+func (t *TieredCache) Context() ContextCache {
+	return &tieredContextCache{
+		TieredCache: t,
+		l2:          NewContextCache(t.l2),
+	}
+}
+
+type tieredContextCache struct {
+	*TieredCache
+	l2 ContextCache
+}
+
+// Get ...
+func (t *tieredContextCache) Get(ctx context.Context, key string) ([]byte, error) {
+	if v, b := t.l1.Get(key).(string); b {
+		return []byte(v), nil
+	}
+	v, e := t.l2.Get(ctx, key)
+	if e != nil {
+		return nil, e
+	}
+	t.fill(key, string(v))
+	return v, nil
+}
+
+// Set ...
+func (t *tieredContextCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if e := t.l2.Set(ctx, key, val, ttl); e != nil {
+		return e
+	}
+	t.setL1(key, string(val), ttl)
+	t.publish(key)
+	return nil
+}
+
+// Delete ...
+func (t *tieredContextCache) Delete(ctx context.Context, key string) error {
+	if e := t.l2.Delete(ctx, key); e != nil {
+		return e
+	}
+	t.l1.Delete(key)
+	t.publish(key)
+	return nil
+}
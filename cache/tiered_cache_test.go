@@ -0,0 +1,89 @@
+package cache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/godcong/wego/cache"
+)
+
+type memoryInvalidator struct {
+	mu  sync.Mutex
+	fns []func(msg string)
+}
+
+func (m *memoryInvalidator) Publish(msg string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, fn := range m.fns {
+		fn(msg)
+	}
+	return nil
+}
+
+func (m *memoryInvalidator) Subscribe(fn func(msg string)) (stop func(), e error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.fns = append(m.fns, fn)
+	return func() {}, nil
+}
+
+// TestTieredCache ...
+func TestTieredCache(t *testing.T) {
+	l2 := cache.NewMapCache()
+	invalidator := &memoryInvalidator{}
+	node1, e := cache.NewTieredCache(l2, cache.TieredCacheL1TTL(time.Minute), cache.TieredCacheInvalidator(invalidator))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer node1.Close()
+	node2, _ := cache.NewTieredCache(l2, cache.TieredCacheL1TTL(time.Minute), cache.TieredCacheInvalidator(invalidator))
+	defer node2.Close()
+
+	node1.Set("token", "old")
+	if v := node2.Get("token"); v != "old" {
+		t.Errorf("wrong value:%v", v)
+	}
+	//forced refresh on node1 evict the L1 of node2
+	node1.Set("token", "new")
+	if v := node2.Get("token"); v != "new" {
+		t.Errorf("L1 should be invalidated,got %v", v)
+	}
+
+	//L1 never outlive L2
+	l2.SetWithTTL("short", "value", 1)
+	if v := node1.Get("short"); v != "value" {
+		t.Errorf("wrong value:%v", v)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if v := node1.Get("short"); v != nil {
+		t.Errorf("L1 outlive L2:%v", v)
+	}
+}
+
+// TestTieredCache_NoL1 ...
+func TestTieredCache_NoL1(t *testing.T) {
+	l2 := cache.NewMapCache()
+	node, e := cache.NewTieredCache(l2, cache.TieredCacheL1TTL(0))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer node.Close()
+
+	node.SetWithTTL("short", "value", 1)
+	if v := node.Get("short"); v != "value" {
+		t.Errorf("wrong value:%v", v)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if v := node.Get("short"); v != nil {
+		t.Errorf("L1 outlive L2:%v", v)
+	}
+
+	//the change of L2 is seen at once without L1
+	node.Set("token", "old")
+	l2.Set("token", "new")
+	if v := node.Get("token"); v != "new" {
+		t.Errorf("L1 should be skipped,got %v", v)
+	}
+}
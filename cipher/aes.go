@@ -102,3 +102,69 @@ func (c *cryptAES256ECB) Decrypt(data interface{}) ([]byte, error) {
 func (c *cryptAES256ECB) Type() CryptType {
 	return AES256ECB
 }
+
+// GCMData the data of AEAD_AES_256_GCM,Text is the plain text to encrypt,
+// Ciphertext is base64 encoded with the tag
+type GCMData struct {
+	Text           string `json:"-"`
+	Ciphertext     string `json:"ciphertext"`
+	Nonce          string `json:"nonce"`
+	AssociatedData string `json:"associated_data"`
+}
+
+type cryptAES256GCM struct {
+	key []byte
+}
+
+// NewAES256GCM the key is the 32 bytes api v3 key
+func NewAES256GCM(opts *Options) Cipher {
+	return &cryptAES256GCM{
+		key: []byte(opts.Key),
+	}
+}
+
+func (c *cryptAES256GCM) aead(nonce string) (cipher.AEAD, error) {
+	if len(c.key) != 32 {
+		return nil, xerrors.Errorf("wrong key size:%d", len(c.key))
+	}
+	block, e := aes.NewCipher(c.key)
+	if e != nil {
+		return nil, e
+	}
+	return cipher.NewGCMWithNonceSize(block, len(nonce))
+}
+
+// Encrypt encrypt the Text of *GCMData,return the base64 encoded ciphertext
+func (c *cryptAES256GCM) Encrypt(data interface{}) ([]byte, error) {
+	d, e := parseGCM(data)
+	if e != nil {
+		return nil, e
+	}
+	aead, e := c.aead(d.Nonce)
+	if e != nil {
+		return nil, e
+	}
+	return Base64Encode(aead.Seal(nil, []byte(d.Nonce), []byte(d.Text), []byte(d.AssociatedData))), nil
+}
+
+// Decrypt decrypt the Ciphertext of *GCMData
+func (c *cryptAES256GCM) Decrypt(data interface{}) ([]byte, error) {
+	d, e := parseGCM(data)
+	if e != nil {
+		return nil, e
+	}
+	cipherText, e := Base64DecodeString(d.Ciphertext)
+	if e != nil {
+		return nil, xerrors.Errorf("wrong data:%w", e)
+	}
+	aead, e := c.aead(d.Nonce)
+	if e != nil {
+		return nil, e
+	}
+	return aead.Open(nil, []byte(d.Nonce), cipherText, []byte(d.AssociatedData))
+}
+
+// Type ...
+func (c *cryptAES256GCM) Type() CryptType {
+	return AES256GCM
+}
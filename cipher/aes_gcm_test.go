@@ -0,0 +1,57 @@
+package cipher
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+// gcm test case 16 of The Galois/Counter Mode of Operation
+var gcmKnownAnswer = struct {
+	key, nonce, aad, plain, cipher string
+}{
+	key:    "feffe9928665731c6d6a8f9467308308feffe9928665731c6d6a8f9467308308",
+	nonce:  "cafebabefacedbaddecaf888",
+	aad:    "feedfacedeadbeeffeedfacedeadbeefabaddad2",
+	plain:  "d9313225f88406e5a55909c5aff5269a86a7a9531534f7da2e4c303d8a318a721c3c0c95956809532fcf0e2449a6b525b16aedf5aa0de657ba637b39",
+	cipher: "522dc1f099567d07f47f37a32a84427d643a8cdcbfe5c0c97598a2bd2555d1aa8cb08e48590dbb3da7b08b1056828838c5f61e6393ba7a0abcc9f662" + "76fc6ece0f4e1768cddf8853bb2d551b",
+}
+
+func gcmHex(t *testing.T, s string) string {
+	b, e := hex.DecodeString(s)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return string(b)
+}
+
+// TestCryptAES256GCM ...
+func TestCryptAES256GCM(t *testing.T) {
+	c := New(AES256GCM, OptionKey(gcmHex(t, gcmKnownAnswer.key)))
+	data := &GCMData{
+		Text:           gcmHex(t, gcmKnownAnswer.plain),
+		Nonce:          gcmHex(t, gcmKnownAnswer.nonce),
+		AssociatedData: gcmHex(t, gcmKnownAnswer.aad),
+	}
+	b, e := c.Encrypt(data)
+	if e != nil {
+		t.Fatal(e)
+	}
+	cipherText, _ := Base64DecodeString(string(b))
+	if hex.EncodeToString(cipherText) != gcmKnownAnswer.cipher {
+		t.Fatalf("wrong cipher text:%x", cipherText)
+	}
+
+	data.Ciphertext = string(b)
+	b, e = c.Decrypt(*data)
+	if e != nil || hex.EncodeToString(b) != gcmKnownAnswer.plain {
+		t.Fatal(hex.EncodeToString(b), e)
+	}
+
+	data.AssociatedData = "certificate"
+	if _, e := c.Decrypt(data); e == nil {
+		t.Error("decrypt with wrong associated data should fail")
+	}
+	if _, e := New(AES256GCM, OptionKey("short")).Decrypt(data); e == nil {
+		t.Error("decrypt with wrong key size should fail")
+	}
+}
@@ -31,14 +31,15 @@ func TestRefundedNotify_ServeHTTP(t *testing.T) {
 	payKey := "aTKnSUcTkbEnhwQNdutWkQxAjnhAz2jK"
 
 	key := strings.ToLower(string(util.SignMD5(payKey, "")))
-	log.Println(key)
+	t.Log(key)
 
-	ecb := New(AES256ECB, &Option{
-		Key: key,
-	})
+	ecb := New(AES256ECB, OptionKey(key))
 
 	d, err := ecb.Decrypt([]byte(reqInfo))
-	t.Error(err)
+	if err != nil {
+		t.Error(err)
+		return
+	}
 	t.Log(string(d))
 	err = xml.Unmarshal(d, &maps)
 	t.Log(maps)
@@ -59,7 +60,7 @@ var validateDecryptCryptAES128CBC = []byte(`<xml>
 // TestPrpCrypt_Encrypt ...
 func TestCryptAES128CBC(t *testing.T) {
 	k, _ := base64.RawStdEncoding.DecodeString(encodingAesKey)
-	log.Println(string(k))
+	t.Log(string(k))
 	prp := &cryptAES128CBC{
 		iv:  k[:16],
 		key: k,
@@ -14,6 +14,7 @@ const (
 	AES256ECB           = iota
 	BizMsg              = iota
 	RSA
+	AES256GCM
 )
 
 // InstanceFunc ...
@@ -31,6 +32,7 @@ var cipherList = []InstanceFunc{
 	AES256ECB: NewAES256ECB,
 	BizMsg:    NewBizMsg,
 	RSA:       NewRSA,
+	AES256GCM: NewAES256GCM,
 }
 
 // Options ...
@@ -124,6 +126,18 @@ func parseBizMsg(data interface{}) (d *BizMsgData, e error) {
 	return
 }
 
+func parseGCM(data interface{}) (d *GCMData, e error) {
+	switch tmp := data.(type) {
+	case *GCMData:
+		d = tmp
+	case GCMData:
+		d = &tmp
+	default:
+		e = xerrors.New("wrong type inputed")
+	}
+	return
+}
+
 /*Base64Encode Base64Encode */
 func Base64Encode(b []byte) []byte {
 	buf := make([]byte, base64.StdEncoding.EncodedLen(len(b)))
@@ -1,9 +1,11 @@
 package cipher
 
 import (
+	"crypto"
 	"crypto/rand"
 	"crypto/rsa"
 	"crypto/sha1"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
 	"encoding/pem"
@@ -20,6 +22,13 @@ var (
 	ErrorNotRSAPublicKey     = errors.New("key is not a valid RSA public key")
 )
 
+// Signer sign and verify with the keys of cipher,the sign is base64 encoded
+type Signer interface {
+	Sign(data interface{}) ([]byte, error)
+	Verify(data interface{}, sign []byte) error
+}
+
+// cryptRSA encrypt with RSA-OAEP(SHA-1) and sign with SHA256withRSA(RSASSA-PKCS1-v1_5)
 type cryptRSA struct {
 	privateKey []byte
 	publicKey  []byte
@@ -73,6 +82,44 @@ func (*cryptRSA) Type() CryptType {
 	return RSA
 }
 
+// Sign sign the data with private key
+func (c *cryptRSA) Sign(data interface{}) ([]byte, error) {
+	key, err := ParseRSAPrivateKeyFromPEM(c.privateKey)
+	if err != nil {
+		return nil, xerrors.Errorf("ParseRSAPrivateKeyFromPEM:%w", err)
+	}
+	sign, err := SignSHA256WithRSA(key, parseBytes(data))
+	if err != nil {
+		return nil, err
+	}
+	return Base64Encode(sign), nil
+}
+
+// Verify verify the base64 encoded sign with public key or certificate
+func (c *cryptRSA) Verify(data interface{}, sign []byte) error {
+	key, err := ParseRSAPublicKeyFromPEM(c.publicKey)
+	if err != nil {
+		return xerrors.Errorf("ParseRSAPublicKeyFromPEM:%w", err)
+	}
+	s, err := Base64Decode(sign)
+	if err != nil {
+		return err
+	}
+	return VerifySHA256WithRSA(key, parseBytes(data), s)
+}
+
+// SignSHA256WithRSA RSASSA-PKCS1-v1_5 with SHA-256
+func SignSHA256WithRSA(key *rsa.PrivateKey, data []byte) ([]byte, error) {
+	hashed := sha256.Sum256(data)
+	return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, hashed[:])
+}
+
+// VerifySHA256WithRSA ...
+func VerifySHA256WithRSA(key *rsa.PublicKey, data, sign []byte) error {
+	hashed := sha256.Sum256(data)
+	return rsa.VerifyPKCS1v15(key, crypto.SHA256, hashed[:], sign)
+}
+
 /*ParseRSAPrivateKeyFromPEM Parse PEM encoded PKCS1 or PKCS8 private key */
 func ParseRSAPrivateKeyFromPEM(key []byte) (*rsa.PrivateKey, error) {
 	block, _ := pem.Decode(key)
@@ -80,19 +127,21 @@ func ParseRSAPrivateKeyFromPEM(key []byte) (*rsa.PrivateKey, error) {
 		return nil, ErrorKeyMustBePEMEncoded
 	}
 
-	pkey, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if pkey, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return pkey, nil
+	}
+	parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes)
 	if err != nil {
-		if parsedKey, err := x509.ParsePKCS8PrivateKey(block.Bytes); err != nil {
-			var ok bool
-			if pkey, ok = parsedKey.(*rsa.PrivateKey); !ok {
-				return nil, ErrorNotRSAPrivateKey
-			}
-		}
+		return nil, err
+	}
+	pkey, ok := parsedKey.(*rsa.PrivateKey)
+	if !ok {
+		return nil, ErrorNotRSAPrivateKey
 	}
 	return pkey, nil
 }
 
-/*ParseRSAPublicKeyFromPEM Parse PEM encoded PKCS1 or PKCS8 public key */
+/*ParseRSAPublicKeyFromPEM Parse PEM encoded PKIX or PKCS1 public key,or the public key of X.509 certificate */
 func ParseRSAPublicKeyFromPEM(key []byte) (*rsa.PublicKey, error) {
 	block, _ := pem.Decode(key)
 	if block == nil {
@@ -102,8 +151,10 @@ func ParseRSAPublicKeyFromPEM(key []byte) (*rsa.PublicKey, error) {
 	// Parse the key
 	parsedKey, err := x509.ParsePKIXPublicKey(block.Bytes)
 	if err != nil {
-		if cert, err := x509.ParseCertificate(block.Bytes); err == nil {
+		if cert, e := x509.ParseCertificate(block.Bytes); e == nil {
 			parsedKey = cert.PublicKey
+		} else if pkey, e := x509.ParsePKCS1PublicKey(block.Bytes); e == nil {
+			return pkey, nil
 		} else {
 			return nil, err
 		}
@@ -0,0 +1,65 @@
+package cipher
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+)
+
+// TestCryptRSA_Sign ...
+func TestCryptRSA_Sign(t *testing.T) {
+	key, e := rsa.GenerateKey(rand.Reader, 2048)
+	if e != nil {
+		t.Fatal(e)
+	}
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(key)
+	pkixKey, _ := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "wechatpay"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	cert, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	privates := map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)},
+		"pkcs8": {Type: "PRIVATE KEY", Bytes: pkcs8},
+	}
+	publics := map[string]*pem.Block{
+		"pkcs1": {Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&key.PublicKey)},
+		"pkix":  {Type: "PUBLIC KEY", Bytes: pkixKey},
+		"x509":  {Type: "CERTIFICATE", Bytes: cert},
+	}
+	for pn, private := range privates {
+		for bn, public := range publics {
+			c := New(RSA, OptionPrivate(string(pem.EncodeToMemory(private))), OptionPublic(string(pem.EncodeToMemory(public))))
+			encrypted, e := c.Encrypt("6225760000000000")
+			if e != nil {
+				t.Fatal(pn, bn, e)
+			}
+			decrypted, e := c.Decrypt(encrypted)
+			if e != nil || string(decrypted) != "6225760000000000" {
+				t.Fatal(pn, bn, string(decrypted), e)
+			}
+			signer := c.(Signer)
+			sign, e := signer.Sign("GET\n/v3/certificates\n")
+			if e != nil {
+				t.Fatal(pn, bn, e)
+			}
+			if e := signer.Verify("GET\n/v3/certificates\n", sign); e != nil {
+				t.Error(pn, bn, e)
+			}
+			if e := signer.Verify("POST\n/v3/certificates\n", sign); e == nil {
+				t.Error(pn, bn, "verify a wrong message should fail")
+			}
+		}
+	}
+}
@@ -10,6 +10,8 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/url"
+	"sync"
 	"time"
 )
 
@@ -39,21 +41,46 @@ type RequestContent struct {
 	Method string
 	URL    string
 	Query  util.Map
+	Header http.Header
 	Body   *RequestBody
+	// Stream the body of response is read from connection,close the Responder after read
+	Stream bool
 }
 
 // Client ...
 type Client struct {
-	context   context.Context
-	TLSConfig *tls.Config
-	BodyType  BodyType
-	//useSafe   bool
-	//safeCert    *SafeCertProperty
+	context     context.Context
+	TLSConfig   *tls.Config
+	BodyType    BodyType
 	accessToken *AccessToken
-	//timeout     int64
-	//keepAlive   int64
+	transport   *http.Transport
+	config      transportConfig
+	proxy       func(*http.Request) (*url.URL, error)
+	once        sync.Once
+	httpClient  *http.Client
+	httpErr     error
+	retry       *RetryPolicy
+	middlewares []Middleware
 }
 
+// transportConfig holds the tunable transport values,zero means default
+type transportConfig struct {
+	dialTimeout           time.Duration
+	keepAlive             time.Duration
+	tlsHandshakeTimeout   time.Duration
+	responseHeaderTimeout time.Duration
+	idleConnTimeout       time.Duration
+	maxIdleConnsPerHost   int
+	maxConnsPerHost       int
+}
+
+const defaultTLSHandshakeTimeout = 10 * time.Second
+const defaultIdleConnTimeout = 90 * time.Second
+const defaultMaxIdleConnsPerHost = 16
+
+var defaultTransport *http.Transport
+var defaultTransportOnce sync.Once
+
 // UseSafe ...
 func (obj *Client) UseSafe() bool {
 	return obj.TLSConfig != nil
@@ -104,14 +131,34 @@ func (obj *Client) MustToken() (token util.Map) {
 	return token
 }
 
+// tokenQuery combine the access token into query when client has one
+func (obj *Client) tokenQuery(query util.Map) util.Map {
+	if obj.accessToken == nil {
+		return query
+	}
+	return util.CombineMaps(query, obj.MustToken())
+}
+
 // Post ...
 func (obj *Client) Post(ctx context.Context, url string, query util.Map, body interface{}) Responder {
 	log.Debug("post ", url, body)
 	return obj.do(ctx, &RequestContent{
 		Method: POST,
 		URL:    url,
-		Query:  util.CombineMaps(query, obj.MustToken()),
+		Query:  obj.tokenQuery(query),
+		Body:   buildBody(body, obj.BodyType),
+	})
+}
+
+// PostStream post and read the body of response from connection,the Bytes is nil when it succeeded
+func (obj *Client) PostStream(ctx context.Context, url string, query util.Map, body interface{}) Responder {
+	log.Debug("post stream ", url, body)
+	return obj.do(ctx, &RequestContent{
+		Method: POST,
+		URL:    url,
+		Query:  obj.tokenQuery(query),
 		Body:   buildBody(body, obj.BodyType),
+		Stream: true,
 	})
 }
 
@@ -121,29 +168,82 @@ func (obj *Client) Get(ctx context.Context, url string, query util.Map) Responde
 	return obj.do(ctx, &RequestContent{
 		Method: POST,
 		URL:    url,
-		Query:  util.CombineMaps(query, obj.MustToken()),
+		Query:  obj.tokenQuery(query),
 		Body:   buildBody(nil, obj.BodyType),
 	})
 }
 
-// HTTPClient ...
+// HTTPClient return the long-lived http client,the transport is built only once
 func (obj *Client) HTTPClient() (*http.Client, error) {
-	return buildHTTPClient(obj, obj.UseSafe())
+	obj.once.Do(func() {
+		obj.httpClient, obj.httpErr = buildHTTPClient(obj, obj.UseSafe())
+	})
+	return obj.httpClient, obj.httpErr
 }
 
-// do ...
+// Transport return the transport used by client
+func (obj *Client) Transport() (*http.Transport, error) {
+	cli, e := obj.HTTPClient()
+	if e != nil {
+		return nil, e
+	}
+	if t, b := cli.Transport.(*http.Transport); b {
+		return t, nil
+	}
+	return nil, xerrors.New("transport is not *http.Transport")
+}
+
+// do run the middlewares around the (retried) round trip
 func (obj *Client) do(ctx context.Context, content *RequestContent) Responder {
+	var rt RoundTrip = obj.roundTrip
+	if obj.retry != nil {
+		rt = func(ctx context.Context, content *RequestContent) Responder {
+			return obj.retry.Do(ctx, content, obj.roundTrip)
+		}
+	}
+	if obj.accessToken != nil {
+		rt = obj.tokenRefresher(rt)
+	}
+	return ChainMiddleware(rt, obj.middlewares...)(ctx, content)
+}
+
+// tokenRefresher refresh the invalid access token once and replay the request
+func (obj *Client) tokenRefresher(next RoundTrip) RoundTrip {
+	return func(ctx context.Context, content *RequestContent) Responder {
+		resp := next(ctx, content)
+		if !IsTokenExpired(resp.Error()) || !replayable(content) {
+			return resp
+		}
+		stale := content.Query.GetString(accessTokenKey)
+		if stale == "" {
+			return resp
+		}
+		token := obj.accessToken.RefreshStale(stale)
+		if token == nil || token.AccessToken == "" {
+			return resp
+		}
+		log.Debug("replay with refreshed access token:", content.URL)
+		content.Query.Set(accessTokenKey, token.AccessToken)
+		return next(ctx, content)
+	}
+}
+
+// roundTrip send the request once
+func (obj *Client) roundTrip(ctx context.Context, content *RequestContent) Responder {
 	client, e := obj.HTTPClient()
 	if e != nil {
-		return ErrResponder(xerrors.Errorf("client build err:%+v", e))
+		return ErrResponder(xerrors.Errorf("client build err: %w", e))
 	}
 	request, e := content.BuildRequest()
 	if e != nil {
-		return ErrResponder(xerrors.Errorf("request build err:%+v", e))
+		return ErrResponder(xerrors.Errorf("request build err: %w", e))
 	}
 	response, e := client.Do(request.WithContext(ctx))
 	if e != nil {
-		return ErrResponder(xerrors.Errorf("response get err:%+v", e))
+		return ErrResponder(xerrors.Errorf("response get err: %w", e))
+	}
+	if content.Stream {
+		return BuildStreamResponder(response)
 	}
 	return BuildResponder(response)
 }
@@ -196,51 +296,79 @@ func Context() (context.Context, context.CancelFunc) {
 	return context.WithTimeout(context.Background(), 30*time.Second)
 }
 
-func buildTransport(client *Client) (*http.Transport, error) {
+func (c transportConfig) isDefault() bool {
+	return c == transportConfig{}
+}
+
+func (c transportConfig) dialer() *net.Dialer {
+	return &net.Dialer{
+		Timeout:   durationD(c.dialTimeout, defaultTimeout*time.Second),
+		KeepAlive: durationD(c.keepAlive, defaultKeepAlive*time.Second),
+	}
+}
+
+func durationD(d, def time.Duration) time.Duration {
+	if d == 0 {
+		return def
+	}
+	return d
+}
+
+func intD(i, def int) int {
+	if i == 0 {
+		return def
+	}
+	return i
+}
+
+func newTransport(c transportConfig, proxy func(*http.Request) (*url.URL, error), config *tls.Config) *http.Transport {
 	return &http.Transport{
-		Proxy:       nil,
-		DialContext: (&net.Dialer{
-			//Timeout:   client.TimeOut(),
-			//KeepAlive: client.KeepAlive(),
-		}).DialContext,
-		//Dial:        nil,
-		//DialTLS:     nil,
-		TLSClientConfig: &tls.Config{
+		Proxy:                 proxy,
+		DialContext:           c.dialer().DialContext,
+		TLSClientConfig:       config,
+		TLSHandshakeTimeout:   durationD(c.tlsHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: c.responseHeaderTimeout,
+		IdleConnTimeout:       durationD(c.idleConnTimeout, defaultIdleConnTimeout),
+		MaxIdleConnsPerHost:   intD(c.maxIdleConnsPerHost, defaultMaxIdleConnsPerHost),
+		MaxConnsPerHost:       c.maxConnsPerHost,
+	}
+}
+
+// DefaultTransport return the transport shared by clients without any custom setting
+func DefaultTransport() *http.Transport {
+	defaultTransportOnce.Do(func() {
+		defaultTransport = newTransport(transportConfig{}, nil, &tls.Config{
 			InsecureSkipVerify: true,
-		},
-		//TLSHandshakeTimeout:    0,
-		//DisableKeepAlives:      false,
-		//DisableCompression:     false,
-		//MaxIdleConns:           0,
-		//MaxIdleConnsPerHost:    0,
-		//MaxConnsPerHost:        0,
-		//IdleConnTimeout:        0,
-		//ResponseHeaderTimeout:  0,
-		//ExpectContinueTimeout:  0,
-		//TLSNextProto:           nil,
-		//ProxyConnectHeader:     nil,
-		//MaxResponseHeaderBytes: 0,
-	}, nil
+		})
+	})
+	return defaultTransport
+}
 
+func buildTransport(client *Client) (*http.Transport, error) {
+	if client.config.isDefault() && client.proxy == nil {
+		return DefaultTransport(), nil
+	}
+	return newTransport(client.config, client.proxy, &tls.Config{
+		InsecureSkipVerify: true,
+	}), nil
 }
 
 func buildSafeTransport(client *Client) (*http.Transport, error) {
-	return &http.Transport{
-		DialContext: (&net.Dialer{
-			//Timeout:   client.TimeOut(),
-			//KeepAlive: client.KeepAlive(),
-			//DualStack: true,
-		}).DialContext,
-		TLSClientConfig: client.TLSConfig,
-		Proxy:           nil,
-		//TLSHandshakeTimeout:   10 * time.Second,
-		//ResponseHeaderTimeout: 10 * time.Second,
-		//ExpectContinueTimeout: 1 * time.Second,
-	}, nil
+	return newTransport(client.config, client.proxy, client.TLSConfig), nil
 }
 
 func buildHTTPClient(client *Client, isSafe bool) (cli *http.Client, e error) {
 	cli = new(http.Client)
+	if client.transport != nil {
+		cli.Transport = client.transport
+		if isSafe {
+			//the shared transport has no merchant cert,clone it for the safe client
+			t := client.transport.Clone()
+			t.TLSClientConfig = client.TLSConfig
+			cli.Transport = t
+		}
+		return cli, nil
+	}
 	//判断能否创建safe client
 	fun := buildTransport
 	if isSafe {
@@ -266,10 +394,20 @@ func readBody(r io.ReadCloser) ([]byte, error) {
 
 // BuildRequest ...
 func (c *RequestContent) BuildRequest() (*http.Request, error) {
+	var request *http.Request
+	var e error
 	if c.Body == nil {
-		return http.NewRequest(c.Method, c.URLQuery(), nil)
+		request, e = http.NewRequest(c.Method, c.URLQuery(), nil)
+	} else {
+		request, e = c.Body.RequestBuilder(c.Method, c.URLQuery(), c.Body.BodyInstance)
+	}
+	if e != nil {
+		return nil, e
+	}
+	for k, v := range c.Header {
+		request.Header[k] = v
 	}
-	return c.Body.RequestBuilder(c.Method, c.URLQuery(), c.Body.BodyInstance)
+	return request, nil
 }
 
 // URLQuery ...
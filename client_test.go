@@ -0,0 +1,69 @@
+package wego
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// TestClient_HTTPClient ...
+func TestClient_HTTPClient(t *testing.T) {
+	client := NewClient()
+	c1, e := client.HTTPClient()
+	if e != nil {
+		t.Fatal(e)
+	}
+	c2, _ := client.HTTPClient()
+	if c1 != c2 || c1.Transport != DefaultTransport() {
+		t.Error("default client should reuse the shared transport")
+	}
+
+	custom := NewClient(ClientDialTimeout(3*time.Second), ClientMaxConnsPerHost(8), ClientResponseHeaderTimeout(5*time.Second))
+	tr, e := custom.Transport()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if tr == DefaultTransport() || tr.MaxConnsPerHost != 8 || tr.ResponseHeaderTimeout != 5*time.Second {
+		t.Errorf("wrong custom transport:%+v", tr)
+	}
+
+	shared := NewClient(ClientTransport(tr))
+	if tr2, _ := shared.Transport(); tr2 != tr {
+		t.Error("transport should be shared")
+	}
+}
+
+// TestClient_SafeTransport ...
+func TestClient_SafeTransport(t *testing.T) {
+	var serial string
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			serial = r.TLS.PeerCertificates[0].SerialNumber.String()
+		}
+	}))
+	server.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	server.StartTLS()
+	defer server.Close()
+
+	_, cert := testV3Cert(t, 1001)
+	shared := &http.Transport{MaxConnsPerHost: 4}
+	payment := NewPayment(&PaymentProperty{SafeCert: cert}, PaymentClientOption(ClientTransport(shared)))
+	tr, e := payment.SafeClient().Transport()
+	if e != nil {
+		t.Fatal(e)
+	}
+	if tr == shared || tr.MaxConnsPerHost != 4 {
+		t.Fatal("safe client should clone the shared transport")
+	}
+	cli, _ := payment.SafeClient().HTTPClient()
+	resp, e := cli.Get(server.URL)
+	if e != nil {
+		t.Fatal(e)
+	}
+	_ = resp.Body.Close()
+	if serial != "1001" {
+		t.Errorf("wrong client cert:%s", serial)
+	}
+}
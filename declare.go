@@ -14,6 +14,8 @@ const APIMCHHK = "https://apihk.mch.weixin.qq.com"
 // APIMCHDefault ...
 const APIMCHDefault = "https://api.mch.weixin.qq.com"
 
+const v3Certificates = "/v3/certificates"
+
 const apiWeixin = "https://api.weixin.qq.com"
 const oauth2Authorize = "https://open.weixin.qq.com/connect/oauth2/authorize"
 const oauth2AccessToken = "https://api.weixin.qq.com/sns/oauth2/access_token"
@@ -73,6 +75,7 @@ const defaultTimeout = 30
 /*accessTokenKey 键值 */
 const accessTokenKey = "access_token"
 const accessToken = "/cgi-bin/token"
+const stableAccessToken = "/cgi-bin/stable_token"
 
 const getKFList = "/cgi-bin/customservice/getkflist"
 
@@ -0,0 +1,179 @@
+package wego
+
+import (
+	"fmt"
+
+	"github.com/godcong/wego/util"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+/*error codes of wechat api */
+const (
+	ErrCodeSystemBusy         = -1
+	ErrCodeInvalidCredential  = 40001
+	ErrCodeInvalidAccessToken = 40014
+	ErrCodeAccessTokenExpired = 42001
+	ErrCodeAPIFreqLimit       = 45009
+	ErrCodeAPIMinuteLimit     = 45011
+)
+
+/*error codes of payment api */
+const (
+	PayErrCodeSystemError      = "SYSTEMERROR"
+	PayErrCodeFrequencyLimited = "FREQUENCY_LIMITED"
+	PayErrCodeOrderPaid        = "ORDERPAID"
+	PayErrCodeOrderClosed      = "ORDERCLOSED"
+	PayErrCodeOrderNotExist    = "ORDERNOTEXIST"
+	PayErrCodeUserPaying       = "USERPAYING"
+	PayErrCodeBankError        = "BANKERROR"
+	// PayErrCodeNoBillExist no trade in the day of bill,the error_code of downloadbill
+	PayErrCodeNoBillExist = "20002"
+)
+
+// APIError the error returned by wechat api with a 200 status
+type APIError struct {
+	// ErrCode errcode of json api
+	ErrCode int64
+	// ErrMsg errmsg of json api
+	ErrMsg     string
+	ReturnCode string
+	ReturnMsg  string
+	ResultCode string
+	// PayErrCode err_code of payment api
+	PayErrCode string
+	ErrCodeDes string
+	// StatusCode http status of payment api v3,the code and message are set to PayErrCode and ErrCodeDes
+	StatusCode int
+	Body       []byte
+}
+
+// Error ...
+func (e *APIError) Error() string {
+	if e.ErrCode != 0 {
+		return fmt.Sprintf("code:%d,msg:%s", e.ErrCode, e.ErrMsg)
+	}
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("status:%d,code:%s,message:%s", e.StatusCode, e.PayErrCode, e.ErrCodeDes)
+	}
+	if e.ReturnCode != "SUCCESS" {
+		return fmt.Sprintf("return_code:%s,return_msg:%s", e.ReturnCode, e.ReturnMsg)
+	}
+	return fmt.Sprintf("result_code:%s,err_code:%s,err_code_des:%s", e.ResultCode, e.PayErrCode, e.ErrCodeDes)
+}
+
+// jsonAPIError check errcode in json body
+func jsonAPIError(body []byte, m util.Map) error {
+	code, _ := m.GetInt64("errcode")
+	if code == 0 {
+		return nil
+	}
+	return &APIError{
+		ErrCode: code,
+		ErrMsg:  m.GetString("errmsg"),
+		Body:    body,
+	}
+}
+
+// xmlAPIError check return_code and result_code in payment xml body
+func xmlAPIError(body []byte, m util.Map) error {
+	returnCode := m.GetString("return_code")
+	resultCode := m.GetString("result_code")
+	if returnCode != "FAIL" && resultCode != "FAIL" {
+		return nil
+	}
+	return &APIError{
+		ReturnCode: returnCode,
+		ReturnMsg:  m.GetString("return_msg"),
+		ResultCode: resultCode,
+		PayErrCode: util.MustString(m.GetString("err_code"), m.GetString("error_code")),
+		ErrCodeDes: m.GetString("err_code_des"),
+		Body:       body,
+	}
+}
+
+// v3APIError convert the StatusError with body {"code":"","message":""} of payment api v3
+func v3APIError(err error) error {
+	var se *StatusError
+	if !xerrors.As(err, &se) || len(se.Body) == 0 {
+		return err
+	}
+	var m util.Map
+	if e := jsoniter.Unmarshal(se.Body, &m); e != nil || m.GetString("code") == "" {
+		return err
+	}
+	return &APIError{
+		PayErrCode: m.GetString("code"),
+		ErrCodeDes: m.GetString("message"),
+		StatusCode: se.StatusCode,
+		Body:       se.Body,
+	}
+}
+
+// AsAPIError get the api error from err
+func AsAPIError(err error) (*APIError, bool) {
+	var e *APIError
+	if err != nil && xerrors.As(err, &e) {
+		return e, true
+	}
+	return nil, false
+}
+
+// IsErrCode check err is an api error with one of the codes
+func IsErrCode(err error, codes ...int64) bool {
+	if e, b := AsAPIError(err); b {
+		for _, code := range codes {
+			if e.ErrCode == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsPayErrCode check err is a payment api error with one of the codes
+func IsPayErrCode(err error, codes ...string) bool {
+	if e, b := AsAPIError(err); b {
+		for _, code := range codes {
+			if e.PayErrCode == code {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsTokenExpired access token was invalid or expired
+func IsTokenExpired(err error) bool {
+	return IsErrCode(err, ErrCodeInvalidCredential, ErrCodeInvalidAccessToken, ErrCodeAccessTokenExpired)
+}
+
+// IsRateLimited api was called too frequently
+func IsRateLimited(err error) bool {
+	return IsErrCode(err, ErrCodeAPIFreqLimit, ErrCodeAPIMinuteLimit) || IsPayErrCode(err, PayErrCodeFrequencyLimited)
+}
+
+// IsSystemBusy wechat system is busy,the request can be retried
+func IsSystemBusy(err error) bool {
+	return IsErrCode(err, ErrCodeSystemBusy) || IsPayErrCode(err, PayErrCodeSystemError)
+}
+
+// IsOrderPaid the order was paid
+func IsOrderPaid(err error) bool {
+	return IsPayErrCode(err, PayErrCodeOrderPaid)
+}
+
+// IsOrderClosed the order was closed
+func IsOrderClosed(err error) bool {
+	return IsPayErrCode(err, PayErrCodeOrderClosed)
+}
+
+// IsOrderNotExist the order was not exist
+func IsOrderNotExist(err error) bool {
+	return IsPayErrCode(err, PayErrCodeOrderNotExist)
+}
+
+// IsUserPaying the user is inputting the password
+func IsUserPaying(err error) bool {
+	return IsPayErrCode(err, PayErrCodeUserPaying)
+}
@@ -0,0 +1,29 @@
+package wego
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestAPIError ...
+func TestAPIError(t *testing.T) {
+	e := JSONResponse([]byte(`{"errcode":40001,"errmsg":"invalid credential"}`)).Error()
+	var apiErr *APIError
+	if !errors.As(e, &apiErr) || apiErr.ErrCode != 40001 || !IsTokenExpired(e) {
+		t.Errorf("wrong json api error:%+v", e)
+	}
+	if e := JSONResponse([]byte(`{"errcode":0,"errmsg":"ok"}`)).Error(); e != nil {
+		t.Error(e)
+	}
+
+	e = XMLResponse([]byte(`<xml><return_code>SUCCESS</return_code><result_code>FAIL</result_code><err_code>ORDERPAID</err_code><err_code_des>paid</err_code_des></xml>`)).Error()
+	if !IsOrderPaid(e) || IsTokenExpired(e) {
+		t.Errorf("wrong xml api error:%+v", e)
+	}
+	if apiErr, b := AsAPIError(e); !b || apiErr.ResultCode != "FAIL" || len(apiErr.Body) == 0 {
+		t.Errorf("wrong xml api error:%+v", apiErr)
+	}
+	if e := XMLResponse([]byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code></xml>`)).Error(); e != nil {
+		t.Error(e)
+	}
+}
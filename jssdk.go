@@ -15,6 +15,10 @@ type JSSDK struct {
 	ticket      *Ticket
 	subAppID    string
 	url         string
+	middlewares []Middleware
+	lockTTL     int64
+	cache       cache.Cache
+	cachePrefix string
 	//CacheKey    func() string
 }
 
@@ -190,24 +194,30 @@ func (obj *JSSDK) BuildConfig(url string, list ...string) *BuildConfig {
 
 // GetTicket ...
 func (obj *JSSDK) GetTicket(s string, refresh bool) string {
-	key := obj.getCacheKey()
-	log.Info("key:", key)
-	if !refresh && cache.Has(key) {
-		if v, b := cache.Get(key).(string); b {
-			log.Infof("cached ticket:%+v", v)
-			return v
-		}
-	}
-
-	tr, e := NewTicket(obj.accessToken).GetTicketRes(s)
+	ticket, _, e := obj.loadTicket(s, refresh)
 	if e != nil {
 		log.Error(e)
 		return ""
 	}
-	log.Infof("ticket:%+v", *tr)
-	cache.SetWithTTL(obj.getCacheKey(), tr.Ticket, tr.ExpiresIn-500)
-	return tr.Ticket
+	return ticket
+}
 
+// loadTicket return the ticket and its life time when it was fetched
+func (obj *JSSDK) loadTicket(s string, refresh bool) (ticket string, life int64, e error) {
+	key := obj.getCacheKey(s)
+	log.Info("key:", key)
+	ticket, e = loadOrRefresh(useCache(obj.cache), key, refresh, obj.lockTTL, func() (string, int64, error) {
+		t := NewTicket(obj.accessToken)
+		t.middlewares = obj.middlewares
+		tr, e := t.GetTicketRes(s)
+		if e != nil {
+			return "", 0, e
+		}
+		log.Infof("ticket:%+v", *tr)
+		life = tokenLife(tr.ExpiresIn)
+		return tr.Ticket, life, nil
+	})
+	return ticket, life, e
 }
 
 // getID ...
@@ -218,9 +228,10 @@ func (obj *JSSDK) getID() string {
 	return obj.AppID
 }
 
-func (obj *JSSDK) getCacheKey() string {
-	c := md5.Sum([]byte("jssdk." + obj.getID()))
-	return fmt.Sprintf("godcong.wego.jssdk.ticket.%x", c[:])
+// getCacheKey the key of ticket type,also the name of refresh flight
+func (obj *JSSDK) getCacheKey(s string) string {
+	c := md5.Sum([]byte("jssdk." + obj.getID() + "." + s))
+	return obj.cachePrefix + fmt.Sprintf("godcong.wego.jssdk.ticket.%s.%x", s, c[:])
 }
 
 func (obj *JSSDK) parse(options ...JSSDKOption) {
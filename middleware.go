@@ -0,0 +1,39 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+)
+
+// RoundTrip send a request content and get the decoded response
+type RoundTrip func(ctx context.Context, content *RequestContent) Responder
+
+// Middleware wrap a round trip,used to logging,metrics,tracing or mutate request
+type Middleware func(next RoundTrip) RoundTrip
+
+// ChainMiddleware chain the middlewares,the first one is the outermost
+func ChainMiddleware(rt RoundTrip, middlewares ...Middleware) RoundTrip {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i] != nil {
+			rt = middlewares[i](rt)
+		}
+	}
+	return rt
+}
+
+// HeaderMiddleware add headers to every request
+func HeaderMiddleware(header http.Header) Middleware {
+	return func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, content *RequestContent) Responder {
+			if content.Header == nil {
+				content.Header = make(http.Header)
+			}
+			for k, v := range header {
+				for _, vv := range v {
+					content.Header.Add(k, vv)
+				}
+			}
+			return next(ctx, content)
+		}
+	}
+}
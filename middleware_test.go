@@ -0,0 +1,41 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/godcong/wego/util"
+)
+
+// TestChainMiddleware ...
+func TestChainMiddleware(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<xml><trace>` + r.Header.Get("X-Trace") + `</trace></xml>`))
+	}))
+	defer server.Close()
+
+	var order []string
+	record := func(name string) Middleware {
+		return func(next RoundTrip) RoundTrip {
+			return func(ctx context.Context, content *RequestContent) Responder {
+				order = append(order, name)
+				resp := next(ctx, content)
+				if resp.Error() != nil {
+					t.Error(name, resp.Error())
+				}
+				return resp
+			}
+		}
+	}
+
+	client := NewClient(ClientMiddleware(record("first"), HeaderMiddleware(http.Header{"X-Trace": {"abc"}}), record("second")))
+	resp := client.Post(context.Background(), server.URL, nil, util.Map{})
+	if resp.ToMap().GetString("trace") != "abc" {
+		t.Error("header not injected", string(resp.Bytes()))
+	}
+	if len(order) != 2 || order[0] != "first" || order[1] != "second" {
+		t.Error("wrong order", order)
+	}
+}
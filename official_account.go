@@ -13,13 +13,14 @@ import (
 // OfficialAccount ...
 type OfficialAccount struct {
 	*OfficialAccountProperty
-	BodyType    BodyType
-	oauth       OAuthProperty
-	client      *Client
-	jssdk       *JSSDK
-	accessToken *AccessToken
-	remoteURL   string
-	localHost   string
+	BodyType      BodyType
+	oauth         OAuthProperty
+	client        *Client
+	clientOptions []ClientOption
+	jssdk         *JSSDK
+	accessToken   *AccessToken
+	remoteURL     string
+	localHost     string
 }
 
 // NewOfficialAccount ...
@@ -45,7 +46,7 @@ func (obj *OfficialAccount) parse(options ...OfficialAccountOption) {
 // Client ...
 func (obj *OfficialAccount) Client() *Client {
 	if obj.client == nil {
-		obj.client = NewClient(ClientBodyType(obj.BodyType), ClientAccessToken(obj.accessToken))
+		obj.client = NewClient(append([]ClientOption{ClientBodyType(obj.BodyType), ClientAccessToken(obj.accessToken)}, obj.clientOptions...)...)
 	}
 	return obj.client
 }
@@ -3,6 +3,11 @@ package wego
 import (
 	"context"
 	"crypto/tls"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/godcong/wego/cache"
 )
 
 // PaymentOption ...
@@ -31,6 +36,18 @@ func PaymentKey(public, privite string) PaymentOption {
 	}
 }
 
+// PaymentClientOption set the options used by both client and safe client
+func PaymentClientOption(options ...ClientOption) PaymentOption {
+	return func(obj *Payment) {
+		obj.clientOptions = append(obj.clientOptions, options...)
+	}
+}
+
+// PaymentMiddleware ...
+func PaymentMiddleware(middlewares ...Middleware) PaymentOption {
+	return PaymentClientOption(ClientMiddleware(middlewares...))
+}
+
 // PaymentRemote ...
 func PaymentRemote(remote string) PaymentOption {
 	return func(obj *Payment) {
@@ -52,6 +69,20 @@ func PaymentSandbox(sandbox *Sandbox) PaymentOption {
 	}
 }
 
+// PaymentCache set the cache of sandbox key,default is cache.DefaultCache()
+func PaymentCache(c cache.Cache) PaymentOption {
+	return func(obj *Payment) {
+		obj.cache = c
+	}
+}
+
+// PaymentCachePrefix prepend the prefix to cache keys,used when environments share one cache
+func PaymentCachePrefix(prefix string) PaymentOption {
+	return func(obj *Payment) {
+		obj.cachePrefix = prefix
+	}
+}
+
 // PaymentNotifyURL ...
 func PaymentNotifyURL(s string) PaymentOption {
 	return func(obj *Payment) {
@@ -73,6 +104,56 @@ func PaymentScannedURL(s string) PaymentOption {
 	}
 }
 
+// PaymentV3Option ...
+type PaymentV3Option func(obj *PaymentV3)
+
+// PaymentV3SerialNo set the serial number of merchant certificate,default is parsed from SafeCert.Cert
+func PaymentV3SerialNo(serialNo string) PaymentV3Option {
+	return func(obj *PaymentV3) {
+		obj.serialNo = serialNo
+	}
+}
+
+// PaymentV3APIKey the api v3 key to decrypt the certificates and notifies
+func PaymentV3APIKey(key string) PaymentV3Option {
+	return func(obj *PaymentV3) {
+		obj.apiV3Key = key
+	}
+}
+
+// PaymentV3Cache set the cache of platform certificates,default is cache.DefaultCache()
+func PaymentV3Cache(c cache.Cache) PaymentV3Option {
+	return func(obj *PaymentV3) {
+		obj.cache = c
+	}
+}
+
+// PaymentV3CachePrefix prepend the prefix to cache keys,used when environments share one cache
+func PaymentV3CachePrefix(prefix string) PaymentV3Option {
+	return func(obj *PaymentV3) {
+		obj.cachePrefix = prefix
+	}
+}
+
+// PaymentV3Remote ...
+func PaymentV3Remote(remote string) PaymentV3Option {
+	return func(obj *PaymentV3) {
+		obj.remoteURL = remote
+	}
+}
+
+// PaymentV3ClientOption set the options used by client
+func PaymentV3ClientOption(options ...ClientOption) PaymentV3Option {
+	return func(obj *PaymentV3) {
+		obj.clientOptions = append(obj.clientOptions, options...)
+	}
+}
+
+// PaymentV3Middleware ...
+func PaymentV3Middleware(middlewares ...Middleware) PaymentV3Option {
+	return PaymentV3ClientOption(ClientMiddleware(middlewares...))
+}
+
 // AccessTokenOption ...
 type AccessTokenOption func(obj *AccessToken)
 
@@ -97,6 +178,41 @@ func AccessTokenKey(key string) AccessTokenOption {
 	}
 }
 
+// AccessTokenMiddleware ...
+func AccessTokenMiddleware(middlewares ...Middleware) AccessTokenOption {
+	return func(obj *AccessToken) {
+		obj.middlewares = append(obj.middlewares, middlewares...)
+	}
+}
+
+// AccessTokenLock lock the refresh across processes with cache,ttl in seconds
+func AccessTokenLock(ttl int64) AccessTokenOption {
+	return func(obj *AccessToken) {
+		obj.lockTTL = ttl
+	}
+}
+
+// AccessTokenSource fetch token with the source instead of /cgi-bin/token
+func AccessTokenSource(source TokenSource) AccessTokenOption {
+	return func(obj *AccessToken) {
+		obj.source = source
+	}
+}
+
+// AccessTokenCache set the cache of token,default is cache.DefaultCache()
+func AccessTokenCache(c cache.Cache) AccessTokenOption {
+	return func(obj *AccessToken) {
+		obj.cache = c
+	}
+}
+
+// AccessTokenCachePrefix prepend the prefix to cache keys,used when environments share one cache
+func AccessTokenCachePrefix(prefix string) AccessTokenOption {
+	return func(obj *AccessToken) {
+		obj.cachePrefix = prefix
+	}
+}
+
 // OfficialAccountOption ...
 type OfficialAccountOption func(obj *OfficialAccount)
 
@@ -117,7 +233,7 @@ func OfficialAccountJSSDK(jssdk *JSSDK) OfficialAccountOption {
 // OfficialAccountAccessTokenProperty ...
 func OfficialAccountAccessTokenProperty(property *AccessTokenProperty) OfficialAccountOption {
 	return func(obj *OfficialAccount) {
-		obj.accessToken = NewAccessToken(property, AccessTokenKey(accessTokenKey), AccessTokenURL(accessToken))
+		obj.accessToken = NewAccessToken(property, AccessTokenKey(accessTokenKey))
 	}
 }
 
@@ -135,6 +251,18 @@ func OfficialAccountBodyType(bodyType BodyType) OfficialAccountOption {
 	}
 }
 
+// OfficialAccountClientOption set the options used by client
+func OfficialAccountClientOption(options ...ClientOption) OfficialAccountOption {
+	return func(obj *OfficialAccount) {
+		obj.clientOptions = append(obj.clientOptions, options...)
+	}
+}
+
+// OfficialAccountMiddleware ...
+func OfficialAccountMiddleware(middlewares ...Middleware) OfficialAccountOption {
+	return OfficialAccountClientOption(ClientMiddleware(middlewares...))
+}
+
 // OfficialAccountRemote ...
 func OfficialAccountRemote(remote string) OfficialAccountOption {
 	return func(obj *OfficialAccount) {
@@ -201,6 +329,88 @@ func ClientBodyType(bt BodyType) ClientOption {
 	}
 }
 
+// ClientTransport share a transport between clients,the safe client uses a clone of it with TLSConfig
+func ClientTransport(transport *http.Transport) ClientOption {
+	return func(obj *Client) {
+		obj.transport = transport
+	}
+}
+
+// ClientDialTimeout ...
+func ClientDialTimeout(d time.Duration) ClientOption {
+	return func(obj *Client) {
+		obj.config.dialTimeout = d
+	}
+}
+
+// ClientKeepAlive ...
+func ClientKeepAlive(d time.Duration) ClientOption {
+	return func(obj *Client) {
+		obj.config.keepAlive = d
+	}
+}
+
+// ClientTLSHandshakeTimeout ...
+func ClientTLSHandshakeTimeout(d time.Duration) ClientOption {
+	return func(obj *Client) {
+		obj.config.tlsHandshakeTimeout = d
+	}
+}
+
+// ClientResponseHeaderTimeout ...
+func ClientResponseHeaderTimeout(d time.Duration) ClientOption {
+	return func(obj *Client) {
+		obj.config.responseHeaderTimeout = d
+	}
+}
+
+// ClientIdleConnTimeout ...
+func ClientIdleConnTimeout(d time.Duration) ClientOption {
+	return func(obj *Client) {
+		obj.config.idleConnTimeout = d
+	}
+}
+
+// ClientMaxIdleConnsPerHost ...
+func ClientMaxIdleConnsPerHost(n int) ClientOption {
+	return func(obj *Client) {
+		obj.config.maxIdleConnsPerHost = n
+	}
+}
+
+// ClientMaxConnsPerHost ...
+func ClientMaxConnsPerHost(n int) ClientOption {
+	return func(obj *Client) {
+		obj.config.maxConnsPerHost = n
+	}
+}
+
+// ClientProxy ...
+func ClientProxy(proxy func(*http.Request) (*url.URL, error)) ClientOption {
+	return func(obj *Client) {
+		obj.proxy = proxy
+	}
+}
+
+// ClientProxyURL ...
+func ClientProxyURL(u *url.URL) ClientOption {
+	return ClientProxy(http.ProxyURL(u))
+}
+
+// ClientRetryPolicy retry the failed request with policy
+func ClientRetryPolicy(policy *RetryPolicy) ClientOption {
+	return func(obj *Client) {
+		obj.retry = policy
+	}
+}
+
+// ClientMiddleware append middlewares to client
+func ClientMiddleware(middlewares ...Middleware) ClientOption {
+	return func(obj *Client) {
+		obj.middlewares = append(obj.middlewares, middlewares...)
+	}
+}
+
 // SandboxOption ...
 type SandboxOption func(obj *Sandbox)
 
@@ -212,13 +422,27 @@ func SandboxSubID(mch, app string) SandboxOption {
 	}
 }
 
+// SandboxCache set the cache of sandbox key,default is the cache of payment
+func SandboxCache(c cache.Cache) SandboxOption {
+	return func(obj *Sandbox) {
+		obj.cache = c
+	}
+}
+
+// SandboxCachePrefix prepend the prefix to cache keys,default is the prefix of payment
+func SandboxCachePrefix(prefix string) SandboxOption {
+	return func(obj *Sandbox) {
+		obj.cachePrefix = prefix
+	}
+}
+
 // JSSDKOption ...
 type JSSDKOption func(obj *JSSDK)
 
 // JSSDKAccessTokenProperty ...
 func JSSDKAccessTokenProperty(property *AccessTokenProperty) JSSDKOption {
 	return func(obj *JSSDK) {
-		obj.accessToken = NewAccessToken(property, AccessTokenKey(accessTokenKey), AccessTokenURL(accessToken))
+		obj.accessToken = NewAccessToken(property, AccessTokenKey(accessTokenKey))
 	}
 }
 
@@ -242,3 +466,110 @@ func JSSDKURL(url string) JSSDKOption {
 		obj.url = url
 	}
 }
+
+// JSSDKMiddleware ...
+func JSSDKMiddleware(middlewares ...Middleware) JSSDKOption {
+	return func(obj *JSSDK) {
+		obj.middlewares = append(obj.middlewares, middlewares...)
+	}
+}
+
+// JSSDKLock lock the ticket refresh across processes with cache,ttl in seconds
+func JSSDKLock(ttl int64) JSSDKOption {
+	return func(obj *JSSDK) {
+		obj.lockTTL = ttl
+	}
+}
+
+// JSSDKCache set the cache of ticket,default is cache.DefaultCache()
+func JSSDKCache(c cache.Cache) JSSDKOption {
+	return func(obj *JSSDK) {
+		obj.cache = c
+	}
+}
+
+// JSSDKCachePrefix prepend the prefix to cache keys,used when environments share one cache
+func JSSDKCachePrefix(prefix string) JSSDKOption {
+	return func(obj *JSSDK) {
+		obj.cachePrefix = prefix
+	}
+}
+
+// TokenManagerOption ...
+type TokenManagerOption func(obj *TokenManager)
+
+// TokenManagerAdvance refresh the value before it was expired
+func TokenManagerAdvance(d time.Duration) TokenManagerOption {
+	return func(obj *TokenManager) {
+		obj.advance = d
+	}
+}
+
+// TokenManagerJitter the max random factor subtracted from the wait time
+func TokenManagerJitter(jitter float64) TokenManagerOption {
+	return func(obj *TokenManager) {
+		obj.jitter = jitter
+	}
+}
+
+// TokenManagerRetryInterval wait time after a failed refresh
+func TokenManagerRetryInterval(d time.Duration) TokenManagerOption {
+	return func(obj *TokenManager) {
+		obj.retryInterval = d
+	}
+}
+
+// TokenManagerOnError callback when refresh failed
+func TokenManagerOnError(fn func(name string, e error)) TokenManagerOption {
+	return func(obj *TokenManager) {
+		obj.onError = fn
+	}
+}
+
+// ClientParamsOption ...
+type ClientParamsOption func(params *clientParams)
+
+// ClientParamsSignType MD5,HMAC-SHA256 or RSA
+func ClientParamsSignType(signType string) ClientParamsOption {
+	return func(params *clientParams) {
+		params.signType = signType
+	}
+}
+
+// ClientParamsURL the code_url of NATIVE or the mweb_url of MWEB
+func ClientParamsURL(u string) ClientParamsOption {
+	return func(params *clientParams) {
+		params.url = u
+	}
+}
+
+// ClientParamsRedirect the redirect_url appended to mweb_url
+func ClientParamsRedirect(redirect string) ClientParamsOption {
+	return func(params *clientParams) {
+		params.redirect = redirect
+	}
+}
+
+// MicroPayOption ...
+type MicroPayOption func(obj *microPay)
+
+// MicroPaySchedule the intervals between the order queries,the last one is repeated,default is 5s
+func MicroPaySchedule(intervals ...time.Duration) MicroPayOption {
+	return func(obj *microPay) {
+		obj.schedule = intervals
+	}
+}
+
+// MicroPayTimeout reverse the order when not paid after timeout,default is 30s
+func MicroPayTimeout(d time.Duration) MicroPayOption {
+	return func(obj *microPay) {
+		obj.timeout = d
+	}
+}
+
+// MicroPayReverseTimeout retry the reverse until timeout when it need recall or failed with SYSTEMERROR,default is 30s
+func MicroPayReverseTimeout(d time.Duration) MicroPayOption {
+	return func(obj *microPay) {
+		obj.reverseTimeout = d
+	}
+}
@@ -13,19 +13,22 @@ import (
 //Payment ...
 type Payment struct {
 	*PaymentProperty
-	BodyType    BodyType
-	client      *Client
-	safeClient  *Client
-	sandbox     *Sandbox
-	publicKey   string
-	privateKey  string
-	subMchID    string
-	subAppID    string
-	remoteURL   string
-	localHost   string
-	notifyURL   string
-	refundedURL string
-	scannedURL  string
+	BodyType      BodyType
+	client        *Client
+	safeClient    *Client
+	clientOptions []ClientOption
+	sandbox       *Sandbox
+	publicKey     string
+	privateKey    string
+	subMchID      string
+	subAppID      string
+	remoteURL     string
+	localHost     string
+	notifyURL     string
+	refundedURL   string
+	scannedURL    string
+	cache         cache.Cache
+	cachePrefix   string
 }
 
 // NewPayment ...
@@ -51,7 +54,7 @@ func (obj *Payment) parseOption(options ...PaymentOption) {
 // Client ...
 func (obj *Payment) Client() *Client {
 	if obj.client == nil {
-		obj.client = NewClient(ClientBodyType(obj.BodyType))
+		obj.client = NewClient(append([]ClientOption{ClientBodyType(obj.BodyType)}, obj.clientOptions...)...)
 	}
 	return obj.client
 }
@@ -59,7 +62,7 @@ func (obj *Payment) Client() *Client {
 // SafeClient ...
 func (obj *Payment) SafeClient() *Client {
 	if obj.safeClient == nil {
-		obj.safeClient = NewClient(ClientBodyType(obj.BodyType), ClientSafeCert(obj.SafeCert))
+		obj.safeClient = NewClient(append([]ClientOption{ClientBodyType(obj.BodyType), ClientSafeCert(obj.SafeCert)}, obj.clientOptions...)...)
 	}
 	return obj.safeClient
 }
@@ -144,6 +147,15 @@ https://api.mch.weixin.qq.com/pay/micropay
 该字段用于上报场景信息，目前支持上报实际门店信息。该字段为JSON对象数据，对象格式为{"store_info":{"id": "门店ID","name": "名称","area_code": "编码","address": "地址" }} ，字段详细说明请点击行前的+展开
 */
 func (obj *Payment) Pay(able util.MapAble) Responder {
+	return obj.pay(context.Background(), able)
+}
+
+func (obj *Payment) pay(ctx context.Context, able util.MapAble) Responder {
+	if v, b := able.(Validator); b {
+		if e := v.Validate(); e != nil {
+			return ErrResponder(e)
+		}
+	}
 	p := able.ToMap()
 	p.Set("appid", obj.AppID)
 
@@ -153,13 +165,14 @@ func (obj *Payment) Pay(able util.MapAble) Responder {
 		p.Set("notify_url", notify)
 	}
 
-	return obj.Request(payMicroPay, p)
+	return obj.post(ctx, obj.SafeClient(), payMicroPay, p)
 }
 
-// UnifyResponse ...
+// UnifyResponse the result of UnifiedOrder
 type UnifyResponse struct {
 	AppID      string `xml:"appid"`
 	CodeURL    string `xml:"code_url"`
+	MWebURL    string `xml:"mweb_url"`
 	DeviceInfo string `xml:"device_info"`
 	ErrCode    string `xml:"err_code"`
 	ErrCodeDes string `xml:"err_code_des"`
@@ -279,6 +292,10 @@ func (obj *Payment) AuthCodeToOpenid(authCode string) Responder {
 //字段名	变量名	必填	类型	示例值	描述
 //对账单日期	bill_date	是	String(8)	20140603	下载对账单的日期，格式:20140603
 func (obj *Payment) BillDownload(bd string, opts ...util.Map) Responder {
+	return obj.Request(payDownloadBill, obj.billDownload(bd, opts...))
+}
+
+func (obj *Payment) billDownload(bd string, opts ...util.Map) util.Map {
 	m := util.CombineMaps(util.Map{
 		"appid":     obj.AppID,
 		"bill_date": bd,
@@ -287,8 +304,7 @@ func (obj *Payment) BillDownload(bd string, opts ...util.Map) Responder {
 	if !m.Has("bill_type") {
 		m.Set("bill_type", "ALL")
 	}
-
-	return obj.Request(payDownloadBill, m)
+	return m
 }
 
 //BillDownloadFundFlow 下载资金账单
@@ -300,14 +316,16 @@ func (obj *Payment) BillDownload(bd string, opts ...util.Map) Responder {
 //Fees 手续费账户
 //压缩账单	tar_type	否	String(8)	GZIP	非必传参数，固定值：GZIP，返回格式为.gzip的压缩包账单。不传则默认为数据流形式。
 func (obj *Payment) BillDownloadFundFlow(bd string, at string, opts ...util.Map) Responder {
-	m := util.CombineMaps(util.Map{
+	return obj.SafeRequest(payDownloadFundFlow, obj.billDownloadFundFlow(bd, at, opts...))
+}
+
+func (obj *Payment) billDownloadFundFlow(bd string, at string, opts ...util.Map) util.Map {
+	return util.CombineMaps(util.Map{
 		"appid":        obj.AppID,
 		"bill_date":    bd,
 		"sign_type":    util.HMACSHA256,
 		"account_type": at,
 	}, opts...)
-
-	return obj.SafeRequest(payDownloadFundFlow, m)
 }
 
 //BillBatchQueryComment 拉取订单评价数据
@@ -442,8 +460,9 @@ https://api.mch.weixin.qq.com/pay/orderquery        （建议接入点:中国国
 签名	sign	是	String(32)	5K8264ILTKCH16CQ2502SI8ZNMTM67VS	签名，详见签名生成算法
 签名类型	sign_type	否	String(32)	HMAC-SHA256	签名类型，目前支持HMAC-SHA256和MD5，默认为MD5
 */
-func (obj *Payment) orderQuery(m util.Map) Responder {
-	return obj.Request(payOrderQuery, m)
+// orderQuery the order query need no cert
+func (obj *Payment) orderQuery(ctx context.Context, m util.Map) Responder {
+	return obj.post(ctx, obj.Client(), payOrderQuery, m)
 }
 
 /*OrderQueryByTransactionID 通过transaction_id查询订单
@@ -452,7 +471,7 @@ func (obj *Payment) orderQuery(m util.Map) Responder {
 微信订单号	transaction_id	String(32)	1009660380201506130728806387	微信的订单号，优先使用
 */
 func (obj *Payment) OrderQueryByTransactionID(id string) Responder {
-	return obj.orderQuery(util.Map{"transaction_id": id})
+	return obj.orderQuery(context.Background(), util.Map{"transaction_id": id})
 }
 
 /*OrderQueryByOutTradeNumber 通过out_trade_no查询订单
@@ -462,7 +481,11 @@ func (obj *Payment) OrderQueryByTransactionID(id string) Responder {
 商户订单号	out_trade_no	String(32)	20150806125346	商户系统内部的订单号，当没提供transaction_id时需要传这个。
 */
 func (obj *Payment) OrderQueryByOutTradeNumber(no string) Responder {
-	return obj.orderQuery(util.Map{"out_trade_no": no})
+	return obj.orderQueryByOutTradeNumber(context.Background(), no)
+}
+
+func (obj *Payment) orderQueryByOutTradeNumber(ctx context.Context, no string) Responder {
+	return obj.orderQuery(ctx, util.Map{"out_trade_no": no})
 }
 
 /*RedPackInfo 查询红包记录
@@ -621,6 +644,34 @@ func (obj *Payment) RedPackSendGroup(m util.Map) Responder {
 	return obj.SafeRequest(mmpaymkttransfersSendGroupRedPack, m)
 }
 
+// UnifiedOrder 统一下单,the request is validated before sent
+func (obj *Payment) UnifiedOrder(req *UnifiedOrderRequest) (*UnifyResponse, error) {
+	if e := req.Validate(); e != nil {
+		return nil, e
+	}
+	resp := obj.Unify(req.ToMap())
+	if e := resp.Error(); e != nil {
+		return nil, e
+	}
+	var result UnifyResponse
+	if e := resp.Unmarshal(&result); e != nil {
+		return nil, xerrors.Errorf("unmarshal unify response: %w", e)
+	}
+	return &result, nil
+}
+
+// Refund 申请退款,the request is validated before sent
+func (obj *Payment) Refund(req *RefundRequest) Responder {
+	if e := req.Validate(); e != nil {
+		return ErrResponder(e)
+	}
+	m := req.ToMap()
+	if !m.Has("notify_url") {
+		m.Set("notify_url", obj.RefundURL())
+	}
+	return obj.SafeRequest(payRefund, m)
+}
+
 /*RefundByOutTradeNumber 按照out_trade_no发起退款
 接口地址
 接口链接:https://api.mch.weixin.qq.com/secapi/pay/refund
@@ -680,7 +731,7 @@ func (obj *Payment) refund(num string, total, refund int, opts ...util.Map) Resp
 		"out_refund_no": num,
 		"total_fee":     strconv.Itoa(total),
 		"refund_fee":    strconv.Itoa(refund),
-	})
+	}, opts...)
 
 	//set notify callback
 	notify := obj.RefundURL()
@@ -890,12 +941,21 @@ func (obj *Payment) TransferToBankCard(p util.Map, opts ...util.Map) Responder {
 
 // Request 默认请求
 func (obj *Payment) Request(url string, p util.Map) Responder {
-	return obj.SafeClient().Post(context.Background(), obj.RequestURL(url), nil, obj.initPay(p))
+	return obj.post(context.Background(), obj.SafeClient(), url, p)
 }
 
 // SafeRequest 安全请求
 func (obj *Payment) SafeRequest(url string, p util.Map) Responder {
-	return obj.SafeClient().Post(context.Background(), obj.RequestURL(url), nil, obj.initPay(p))
+	return obj.post(context.Background(), obj.SafeClient(), url, p)
+}
+
+func (obj *Payment) post(ctx context.Context, client *Client, url string, p util.Map) Responder {
+	return client.Post(ctx, obj.RequestURL(url), nil, obj.initPay(p))
+}
+
+// postStream the body of response is not read,close it after read
+func (obj *Payment) postStream(ctx context.Context, client *Client, url string, p util.Map) Responder {
+	return client.PostStream(ctx, obj.RequestURL(url), nil, obj.initPay(p))
 }
 
 func (obj *Payment) initPay(p util.Map, ignore ...string) util.Map {
@@ -930,8 +990,12 @@ func (obj *Payment) UseSandbox() bool {
 func (obj *Payment) GetKey() string {
 	key := obj.Key
 	if obj.UseSandbox() {
+		c := useCache(obj.sandbox.cache, obj.cache)
 		keyName := obj.sandbox.getCacheKey()
-		cachedKey := cache.Get(keyName)
+		if obj.sandbox.cachePrefix == "" {
+			keyName = obj.cachePrefix + keyName
+		}
+		cachedKey := c.Get(keyName)
 		if cachedKey != nil {
 			log.Debug("cached key:", keyName, cachedKey.(string))
 			key = cachedKey.(string)
@@ -942,7 +1006,7 @@ func (obj *Payment) GetKey() string {
 		if resp.GetString("return_code") == "SUCCESS" {
 			key = resp.GetString("sandbox_signkey")
 			log.Debug("key:", keyName, key)
-			cache.SetWithTTL(keyName, key, 24*3600)
+			c.SetWithTTL(keyName, key, 24*3600)
 		}
 	}
 
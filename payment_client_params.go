@@ -0,0 +1,190 @@
+package wego
+
+import (
+	"crypto/rsa"
+	"net/url"
+	"strings"
+
+	"github.com/godcong/wego/cipher"
+	"github.com/godcong/wego/util"
+	"golang.org/x/xerrors"
+)
+
+// SignTypeRSA the sign type of payment api v3,signed with SHA256withRSA
+const SignTypeRSA = "RSA"
+
+// ClientParams the params to start the payment in front end,only the field of trade type is set.
+// JSAPI is used by WeixinJSBridge.invoke("getBrandWCPayRequest") and wx.requestPayment of mini program
+type ClientParams struct {
+	TradeType string        `json:"trade_type"`
+	JSAPI     *BridgeConfig `json:"jsapi,omitempty"`
+	App       *AppConfig    `json:"app,omitempty"`
+	CodeURL   string        `json:"code_url,omitempty"`
+	MWebURL   string        `json:"mweb_url,omitempty"`
+}
+
+type clientParams struct {
+	signType string
+	url      string
+	redirect string
+}
+
+// clientSigner sign the params with v2 key or v3 private key
+type clientSigner struct {
+	appID      string
+	mchID      string
+	key        string
+	privateKey *rsa.PrivateKey
+}
+
+// ClientParams the signed params of prepay id,the sign type is MD5 by default,
+// HMAC-SHA256 and RSA are set by ClientParamsSignType.the RSA key is the Key of SafeCert
+func (obj *Payment) ClientParams(tradeType, prepayID string, options ...ClientParamsOption) (*ClientParams, error) {
+	params := parseClientParams(util.MD5, options...)
+	signer := &clientSigner{
+		appID: obj.AppID,
+		mchID: obj.MchID,
+	}
+	if obj.subAppID != "" {
+		signer.appID = obj.subAppID
+	}
+	if params.signType == SignTypeRSA {
+		if obj.SafeCert == nil {
+			return nil, xerrors.New("rsa sign type need the SafeCert")
+		}
+		key, e := cipher.ParseRSAPrivateKeyFromPEM(obj.SafeCert.Key)
+		if e != nil {
+			return nil, e
+		}
+		signer.privateKey = key
+	} else {
+		signer.key = obj.GetKey()
+	}
+	return signer.build(tradeType, prepayID, params)
+}
+
+// UnifyClientParams the signed params of unified order,the code_url or mweb_url is set from response
+func (obj *Payment) UnifyClientParams(resp *UnifyResponse, options ...ClientParamsOption) (*ClientParams, error) {
+	u := resp.CodeURL
+	if resp.TradeType == TradeTypeMWEB {
+		u = resp.MWebURL
+	}
+	return obj.ClientParams(resp.TradeType, resp.PrepayID, append([]ClientParamsOption{ClientParamsURL(u)}, options...)...)
+}
+
+// ClientParams the params signed with the merchant private key,appID is the app of prepay id
+func (obj *PaymentV3) ClientParams(appID, tradeType, prepayID string, options ...ClientParamsOption) (*ClientParams, error) {
+	if e := obj.loadKey(); e != nil {
+		return nil, e
+	}
+	signer := &clientSigner{
+		appID:      appID,
+		mchID:      obj.MchID,
+		privateKey: obj.privateKey,
+	}
+	params := parseClientParams(SignTypeRSA, options...)
+	params.signType = SignTypeRSA
+	return signer.build(tradeType, prepayID, params)
+}
+
+func parseClientParams(signType string, options ...ClientParamsOption) *clientParams {
+	params := &clientParams{signType: signType}
+	for _, o := range options {
+		o(params)
+	}
+	return params
+}
+
+func (s *clientSigner) build(tradeType, prepayID string, params *clientParams) (*ClientParams, error) {
+	result := &ClientParams{TradeType: tradeType}
+	var e error
+	switch tradeType {
+	case TradeTypeJSAPI:
+		result.JSAPI, e = s.jsapi(prepayID, params.signType)
+	case TradeTypeAPP:
+		result.App, e = s.app(prepayID, params.signType)
+	case TradeTypeNative:
+		if params.url == "" {
+			return nil, xerrors.New("native need the code_url")
+		}
+		result.CodeURL = params.url
+	case TradeTypeMWEB:
+		if params.url == "" {
+			return nil, xerrors.New("mweb need the mweb_url")
+		}
+		result.MWebURL = params.url
+		if params.redirect != "" {
+			result.MWebURL += "&redirect_url=" + url.QueryEscape(params.redirect)
+		}
+	default:
+		return nil, xerrors.Errorf("unsupported trade type:%s", tradeType)
+	}
+	if e != nil {
+		return nil, e
+	}
+	return result, nil
+}
+
+func (s *clientSigner) jsapi(prepayID, signType string) (*BridgeConfig, error) {
+	if prepayID == "" {
+		return nil, xerrors.New("jsapi need the prepay_id")
+	}
+	config := &BridgeConfig{
+		AppID:     s.appID,
+		NonceStr:  util.GenerateNonceStr(),
+		Package:   strings.Join([]string{"prepay_id", prepayID}, "="),
+		SignType:  signType,
+		TimeStamp: util.Time(),
+	}
+	var e error
+	if signType == SignTypeRSA {
+		config.PaySign, e = s.rsa(config.AppID, config.TimeStamp, config.NonceStr, config.Package)
+		return config, e
+	}
+	config.PaySign = util.GenSign(util.Map{
+		"appId":            config.AppID,
+		"timeStamp":        config.TimeStamp,
+		"nonceStr":         config.NonceStr,
+		"package":          config.Package,
+		"signType":         config.SignType,
+		util.FieldSignType: signType,
+	}, s.key, util.FieldSignType)
+	return config, nil
+}
+
+func (s *clientSigner) app(prepayID, signType string) (*AppConfig, error) {
+	if prepayID == "" {
+		return nil, xerrors.New("app need the prepay_id")
+	}
+	config := &AppConfig{
+		AppID:     s.appID,
+		NonceStr:  util.GenerateNonceStr(),
+		Package:   "Sign=WXPay",
+		PartnerID: s.mchID,
+		PrepayID:  prepayID,
+		TimeStamp: util.Time(),
+	}
+	var e error
+	if signType == SignTypeRSA {
+		config.Sign, e = s.rsa(config.AppID, config.TimeStamp, config.NonceStr, config.PrepayID)
+		return config, e
+	}
+	config.Sign = util.GenSign(util.Map{
+		"appid":            config.AppID,
+		"partnerid":        config.PartnerID,
+		"prepayid":         config.PrepayID,
+		"noncestr":         config.NonceStr,
+		"timestamp":        config.TimeStamp,
+		"package":          config.Package,
+		util.FieldSignType: signType,
+	}, s.key, util.FieldSignType)
+	return config, nil
+}
+
+// rsa the message of v3 client sign is each value end with \n
+func (s *clientSigner) rsa(lines ...string) (string, error) {
+	if s.privateKey == nil {
+		return "", xerrors.New("rsa sign type need the private key")
+	}
+	return signSHA256WithRSA(s.privateKey, v3Message(lines...))
+}
@@ -0,0 +1,53 @@
+package wego
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"testing"
+
+	"github.com/godcong/wego/util"
+)
+
+// TestPayment_ClientParams ...
+func TestPayment_ClientParams(t *testing.T) {
+	key, cert := testV3Cert(t, 0x1A2B)
+	payment := NewPayment(&PaymentProperty{AppID: "wx2421b1c4370ec43b", MchID: "10000100", Key: "192006250b4c09247ec02edce69f6a2d", SafeCert: cert})
+
+	params, e := payment.ClientParams(TradeTypeJSAPI, "wx201410272009395522657a690389285100", ClientParamsSignType(util.HMACSHA256))
+	if e != nil {
+		t.Fatal(e)
+	}
+	c := params.JSAPI
+	sign := util.GenSign(util.Map{
+		"appId":     c.AppID,
+		"timeStamp": c.TimeStamp,
+		"nonceStr":  c.NonceStr,
+		"package":   c.Package,
+		"signType":  util.HMACSHA256,
+		"sign_type": util.HMACSHA256,
+	}, payment.Key, "sign_type")
+	if c.SignType != util.HMACSHA256 || c.PaySign != sign {
+		t.Errorf("wrong jsapi params:%+v", c)
+	}
+
+	params, e = payment.ClientParams(TradeTypeAPP, "wx201410272009395522657a690389285100", ClientParamsSignType(SignTypeRSA))
+	if e != nil {
+		t.Fatal(e)
+	}
+	s, _ := base64.StdEncoding.DecodeString(params.App.Sign)
+	hashed := sha256.Sum256(v3Message(params.App.AppID, params.App.TimeStamp, params.App.NonceStr, params.App.PrepayID))
+	if e := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], s); e != nil {
+		t.Error(e)
+	}
+
+	params, e = payment.UnifyClientParams(&UnifyResponse{TradeType: TradeTypeMWEB, MWebURL: "https://wx.tenpay.com/cgi-bin/mmpayweb-bin/checkmweb?prepay_id=wx2016121516420242444321ca0631331346"},
+		ClientParamsRedirect("https://www.example.com/paid?id=1"))
+	if e != nil || params.MWebURL != "https://wx.tenpay.com/cgi-bin/mmpayweb-bin/checkmweb?prepay_id=wx2016121516420242444321ca0631331346&redirect_url=https%3A%2F%2Fwww.example.com%2Fpaid%3Fid%3D1" {
+		t.Error(params, e)
+	}
+	if _, e := payment.ClientParams(TradeTypeNative, ""); e == nil {
+		t.Error("native without code_url should fail")
+	}
+}
@@ -0,0 +1,185 @@
+package wego
+
+import (
+	"context"
+	"time"
+
+	"github.com/godcong/wego/util"
+	"golang.org/x/xerrors"
+)
+
+const defaultMicroPayInterval = 5 * time.Second
+const defaultMicroPayTimeout = 30 * time.Second
+
+// MicroPayState the final state of micro pay
+type MicroPayState int
+
+/*final states of MicroPayAndWait */
+const (
+	// MicroPayPaid the user paid the order
+	MicroPayPaid MicroPayState = iota
+	// MicroPayClosed the order was not paid and closed
+	MicroPayClosed
+	// MicroPayReversed the order was not paid in time and reversed
+	MicroPayReversed
+	// MicroPayManual the state of order is unknown,check it in the merchant platform
+	MicroPayManual
+)
+
+// String ...
+func (s MicroPayState) String() string {
+	switch s {
+	case MicroPayPaid:
+		return "PAID"
+	case MicroPayClosed:
+		return "CLOSED"
+	case MicroPayReversed:
+		return "REVERSED"
+	}
+	return "MANUAL"
+}
+
+// MicroPayResult ...
+type MicroPayResult struct {
+	State         MicroPayState
+	OutTradeNo    string
+	TransactionID string
+	// Result the last result of micropay,orderquery or reverse
+	Result util.Map
+	// Err the reason of not paid or need manual review
+	Err error
+}
+
+type microPay struct {
+	schedule       []time.Duration
+	timeout        time.Duration
+	reverseTimeout time.Duration
+}
+
+// wait the interval of attempt,the last interval is repeated
+func (m *microPay) wait(attempt int) time.Duration {
+	if len(m.schedule) == 0 {
+		return defaultMicroPayInterval
+	}
+	if attempt >= len(m.schedule) {
+		attempt = len(m.schedule) - 1
+	}
+	return m.schedule[attempt]
+}
+
+// MicroPayAndWait 刷卡支付,等待用户支付完成.
+// USERPAYING,SYSTEMERROR,BANKERROR and the unknown results are polled by OrderQueryByOutTradeNumber,
+// the order is reversed once when it was not paid before timeout or ctx was done.
+// the error is returned only when the request is invalid
+func (obj *Payment) MicroPayAndWait(ctx context.Context, req *MicroPayRequest, options ...MicroPayOption) (*MicroPayResult, error) {
+	if e := req.Validate(); e != nil {
+		return nil, e
+	}
+	m := &microPay{
+		schedule:       []time.Duration{defaultMicroPayInterval},
+		timeout:        defaultMicroPayTimeout,
+		reverseTimeout: defaultMicroPayTimeout,
+	}
+	for _, o := range options {
+		o(m)
+	}
+	timeout, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+
+	result := &MicroPayResult{OutTradeNo: req.OutTradeNo}
+	resp := obj.pay(timeout, req)
+	result.Result = resp.ToMap()
+	e := resp.Error()
+	switch {
+	case e == nil:
+		return result.paid(), nil
+	case IsPayErrCode(e, PayErrCodeUserPaying, PayErrCodeSystemError, PayErrCodeBankError):
+	case IsPayErrCode(e, PayErrCodeOrderPaid):
+		//paid before,query the transaction id
+	default:
+		if _, b := AsAPIError(e); b {
+			result.State, result.Err = MicroPayClosed, e
+			return result, nil
+		}
+		//network error or 5xx,the result is unknown
+	}
+	result.Err = e
+
+	for attempt := 0; ; attempt++ {
+		timer := time.NewTimer(m.wait(attempt))
+		select {
+		case <-timeout.Done():
+			timer.Stop()
+			return obj.microPayReverse(m, result), nil
+		case <-timer.C:
+		}
+		resp := obj.orderQueryByOutTradeNumber(timeout, req.OutTradeNo)
+		if e := resp.Error(); e != nil {
+			log.Debug("micropay query", req.OutTradeNo, e)
+			result.Err = e
+			continue
+		}
+		result.Result = resp.ToMap()
+		switch state := result.Result.GetString("trade_state"); state {
+		case "SUCCESS":
+			return result.paid(), nil
+		case "USERPAYING", "NOTPAY":
+		case "CLOSED", "REVOKED", "PAYERROR":
+			result.State, result.Err = MicroPayClosed, xerrors.Errorf("trade_state:%s", state)
+			return result, nil
+		default:
+			result.State, result.Err = MicroPayManual, xerrors.Errorf("trade_state:%s", state)
+			return result, nil
+		}
+	}
+}
+
+func (r *MicroPayResult) paid() *MicroPayResult {
+	r.State = MicroPayPaid
+	r.TransactionID = r.Result.GetString("transaction_id")
+	r.Err = nil
+	return r
+}
+
+// microPayReverse reverse with a new context,so the order will not be left paying when ctx was canceled.
+// the reverse is retried with the schedule when it need recall or failed with SYSTEMERROR or network error
+func (obj *Payment) microPayReverse(m *microPay, result *MicroPayResult) *MicroPayResult {
+	ctx, cancel := context.WithTimeout(context.Background(), m.reverseTimeout)
+	defer cancel()
+	for attempt := 0; ; attempt++ {
+		resp := obj.post(ctx, obj.SafeClient(), payReverse, util.Map{"out_trade_no": result.OutTradeNo})
+		e := resp.Error()
+		switch {
+		case e == nil:
+			result.Result = resp.ToMap()
+			if result.Result.GetString("recall") != "Y" {
+				result.State, result.Err = MicroPayReversed, nil
+				return result
+			}
+			result.Err = xerrors.New("reverse need recall")
+		case microPayReverseRetryable(e):
+			result.Err = xerrors.Errorf("reverse: %w", e)
+		default:
+			result.State, result.Err = MicroPayManual, xerrors.Errorf("reverse: %w", e)
+			return result
+		}
+		log.Debug("micropay reverse", result.OutTradeNo, result.Err)
+		timer := time.NewTimer(m.wait(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			result.State = MicroPayManual
+			return result
+		case <-timer.C:
+		}
+	}
+}
+
+// microPayReverseRetryable SYSTEMERROR or the error without result
+func microPayReverseRetryable(e error) bool {
+	if IsPayErrCode(e, PayErrCodeSystemError) {
+		return true
+	}
+	_, b := AsAPIError(e)
+	return !b
+}
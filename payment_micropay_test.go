@@ -0,0 +1,81 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestPayment_MicroPayAndWait ...
+func TestPayment_MicroPayAndWait(t *testing.T) {
+	var queries, reversed int32
+	var paidAfter, recallTimes int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		switch {
+		case strings.HasSuffix(r.URL.Path, payMicroPay):
+			_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code><result_code>FAIL</result_code><err_code>USERPAYING</err_code><err_code_des>需要用户输入支付密码</err_code_des></xml>`))
+		case strings.HasSuffix(r.URL.Path, payOrderQuery):
+			if atomic.AddInt32(&queries, 1) == atomic.LoadInt32(&paidAfter) {
+				_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code><trade_state>SUCCESS</trade_state><transaction_id>1008450740201411110005820873</transaction_id></xml>`))
+				return
+			}
+			_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code><trade_state>USERPAYING</trade_state></xml>`))
+		case strings.HasSuffix(r.URL.Path, payReverse):
+			n := atomic.AddInt32(&reversed, 1)
+			if recall := atomic.LoadInt32(&recallTimes); recall < 0 {
+				_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code><result_code>FAIL</result_code><err_code>SYSTEMERROR</err_code></xml>`))
+				return
+			} else if n <= recall {
+				_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code><recall>Y</recall></xml>`))
+				return
+			}
+			_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code><result_code>SUCCESS</result_code><recall>N</recall></xml>`))
+		}
+	}))
+	defer server.Close()
+
+	_, cert := testV3Cert(t, 0x1A2B)
+	payment := NewPayment(&PaymentProperty{AppID: "wx2421b1c4370ec43b", MchID: "10000100", Key: "192006250b4c09247ec02edce69f6a2d", SafeCert: cert},
+		PaymentRemote(server.URL))
+	req := &MicroPayRequest{
+		Body:           "image形象店-深圳腾大- QQ公仔",
+		OutTradeNo:     "1217752501201407033233368018",
+		TotalFee:       888,
+		SpbillCreateIP: "8.8.8.8",
+		AuthCode:       "120061098828009406",
+	}
+
+	atomic.StoreInt32(&paidAfter, 2)
+	result, e := payment.MicroPayAndWait(context.Background(), req, MicroPaySchedule(10*time.Millisecond), MicroPayTimeout(time.Second))
+	if e != nil || result.State != MicroPayPaid || result.TransactionID != "1008450740201411110005820873" {
+		t.Fatal(result, e)
+	}
+
+	atomic.StoreInt32(&paidAfter, -1)
+	result, e = payment.MicroPayAndWait(context.Background(), req, MicroPaySchedule(10*time.Millisecond), MicroPayTimeout(100*time.Millisecond))
+	if e != nil || result.State != MicroPayReversed || atomic.LoadInt32(&reversed) != 1 {
+		t.Fatal(result, e)
+	}
+
+	//reverse again when recall=Y
+	atomic.StoreInt32(&reversed, 0)
+	atomic.StoreInt32(&recallTimes, 2)
+	result, e = payment.MicroPayAndWait(context.Background(), req, MicroPaySchedule(10*time.Millisecond), MicroPayTimeout(50*time.Millisecond))
+	if e != nil || result.State != MicroPayReversed || atomic.LoadInt32(&reversed) != 3 {
+		t.Fatal(result, e, reversed)
+	}
+
+	//manual after the reverse kept failing until timeout
+	atomic.StoreInt32(&reversed, 0)
+	atomic.StoreInt32(&recallTimes, -1)
+	result, e = payment.MicroPayAndWait(context.Background(), req, MicroPaySchedule(10*time.Millisecond), MicroPayTimeout(50*time.Millisecond),
+		MicroPayReverseTimeout(100*time.Millisecond))
+	if e != nil || result.State != MicroPayManual || !IsPayErrCode(result.Err, PayErrCodeSystemError) || atomic.LoadInt32(&reversed) < 2 {
+		t.Fatal(result, e, reversed)
+	}
+}
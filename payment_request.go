@@ -0,0 +1,343 @@
+package wego
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godcong/wego/util"
+	jsoniter "github.com/json-iterator/go"
+)
+
+// PaymentTimeFormat the format of time_start and time_expire:yyyyMMddHHmmss
+const PaymentTimeFormat = "20060102150405"
+
+/*trade types of unified order */
+const (
+	TradeTypeJSAPI  = "JSAPI"
+	TradeTypeNative = "NATIVE"
+	TradeTypeAPP    = "APP"
+	TradeTypeMWEB   = "MWEB"
+)
+
+var outTradeNoRegexp = regexp.MustCompile(`^[0-9A-Za-z_\-|*]+$`)
+var outRefundNoRegexp = regexp.MustCompile(`^[0-9A-Za-z_\-|*@]+$`)
+var authCodeRegexp = regexp.MustCompile(`^1[0-5][0-9]{16}$`)
+
+// Validator the request is validated before sent
+type Validator interface {
+	Validate() error
+}
+
+// FieldError ...
+type FieldError struct {
+	Field  string
+	Reason string
+}
+
+// Error ...
+func (e *FieldError) Error() string {
+	return e.Field + ":" + e.Reason
+}
+
+// ValidationError all the invalid fields of request
+type ValidationError struct {
+	Fields []*FieldError
+}
+
+// Error ...
+func (e *ValidationError) Error() string {
+	s := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		s[i] = f.Error()
+	}
+	return "invalid request fields: " + strings.Join(s, ";")
+}
+
+// Field get the error of field,nil when it is valid
+func (e *ValidationError) Field(field string) *FieldError {
+	for _, f := range e.Fields {
+		if f.Field == field {
+			return f
+		}
+	}
+	return nil
+}
+
+type fieldValidator struct {
+	fields []*FieldError
+}
+
+func (v *fieldValidator) check(ok bool, field, format string, args ...interface{}) {
+	if !ok {
+		v.fields = append(v.fields, &FieldError{Field: field, Reason: fmt.Sprintf(format, args...)})
+	}
+}
+
+// length check the required field and the max bytes of value
+func (v *fieldValidator) length(field, val string, required bool, max int) {
+	if val == "" {
+		v.check(!required, field, "required")
+		return
+	}
+	v.check(len(val) <= max, field, "longer than %d", max)
+}
+
+func (v *fieldValidator) outTradeNo(field, val string) {
+	v.length(field, val, true, 32)
+	v.check(val == "" || outTradeNoRegexp.MatchString(val), field, "only digits,letters and _-|* are allowed")
+}
+
+// outRefundNo out_refund_no allows @ and is longer than out_trade_no
+func (v *fieldValidator) outRefundNo(field, val string) {
+	v.length(field, val, true, 64)
+	v.check(val == "" || outRefundNoRegexp.MatchString(val), field, "only digits,letters and _-|*@ are allowed")
+}
+
+func (v *fieldValidator) fee(field string, val int) {
+	v.check(val > 0, field, "must be positive")
+}
+
+// times time_expire must be 1 minute later than time_start
+func (v *fieldValidator) times(start, expire string) {
+	var s, e time.Time
+	var err error
+	if start != "" {
+		s, err = time.Parse(PaymentTimeFormat, start)
+		v.check(err == nil, "time_start", "must be yyyyMMddHHmmss")
+	}
+	if expire != "" {
+		e, err = time.Parse(PaymentTimeFormat, expire)
+		v.check(err == nil, "time_expire", "must be yyyyMMddHHmmss")
+	}
+	if !s.IsZero() && !e.IsZero() {
+		v.check(e.Sub(s) > time.Minute, "time_expire", "must be more than 1 minute after time_start")
+	}
+}
+
+func (v *fieldValidator) err() error {
+	if v.fields == nil {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields}
+}
+
+// StoreInfo ...
+type StoreInfo struct {
+	ID       string `json:"id"`
+	Name     string `json:"name,omitempty"`
+	AreaCode string `json:"area_code,omitempty"`
+	Address  string `json:"address,omitempty"`
+}
+
+// SceneInfo the scene_info is a json object
+type SceneInfo struct {
+	StoreInfo *StoreInfo `json:"store_info,omitempty"`
+}
+
+// String the json of scene info,empty when nil
+func (s *SceneInfo) String() string {
+	if s == nil {
+		return ""
+	}
+	str, e := jsoniter.MarshalToString(s)
+	if e != nil {
+		log.Error(e)
+	}
+	return str
+}
+
+// setNotEmpty set the not empty values only
+func setNotEmpty(m util.Map, values map[string]string) util.Map {
+	for k, v := range values {
+		if v != "" {
+			m.Set(k, v)
+		}
+	}
+	return m
+}
+
+// UnifiedOrderRequest request of Unify,the appid,mch_id,nonce_str and sign are set by Payment
+type UnifiedOrderRequest struct {
+	DeviceInfo     string
+	Body           string
+	Detail         string
+	Attach         string
+	OutTradeNo     string
+	FeeType        string
+	TotalFee       int
+	SpbillCreateIP string
+	TimeStart      string
+	TimeExpire     string
+	GoodsTag       string
+	NotifyURL      string
+	TradeType      string
+	ProductID      string
+	LimitPay       string
+	OpenID         string
+	Receipt        string
+	SceneInfo      *SceneInfo
+}
+
+// Validate ...
+func (r *UnifiedOrderRequest) Validate() error {
+	v := &fieldValidator{}
+	v.length("device_info", r.DeviceInfo, false, 32)
+	v.length("body", r.Body, true, 128)
+	v.length("detail", r.Detail, false, 6000)
+	v.length("attach", r.Attach, false, 127)
+	v.outTradeNo("out_trade_no", r.OutTradeNo)
+	v.length("fee_type", r.FeeType, false, 16)
+	v.fee("total_fee", r.TotalFee)
+	v.length("spbill_create_ip", r.SpbillCreateIP, r.TradeType != TradeTypeNative, 64)
+	v.times(r.TimeStart, r.TimeExpire)
+	v.length("goods_tag", r.GoodsTag, false, 32)
+	v.length("notify_url", r.NotifyURL, false, 256)
+	switch r.TradeType {
+	case TradeTypeJSAPI, TradeTypeNative, TradeTypeAPP, TradeTypeMWEB:
+	default:
+		v.check(false, "trade_type", "must be one of JSAPI,NATIVE,APP,MWEB")
+	}
+	v.length("product_id", r.ProductID, r.TradeType == TradeTypeNative, 32)
+	v.length("openid", r.OpenID, r.TradeType == TradeTypeJSAPI, 128)
+	v.length("scene_info", r.SceneInfo.String(), false, 256)
+	return v.err()
+}
+
+// ToMap ...
+func (r *UnifiedOrderRequest) ToMap() util.Map {
+	m := util.Map{
+		"total_fee": strconv.Itoa(r.TotalFee),
+	}
+	return setNotEmpty(m, map[string]string{
+		"device_info":      r.DeviceInfo,
+		"body":             r.Body,
+		"detail":           r.Detail,
+		"attach":           r.Attach,
+		"out_trade_no":     r.OutTradeNo,
+		"fee_type":         r.FeeType,
+		"spbill_create_ip": r.SpbillCreateIP,
+		"time_start":       r.TimeStart,
+		"time_expire":      r.TimeExpire,
+		"goods_tag":        r.GoodsTag,
+		"notify_url":       r.NotifyURL,
+		"trade_type":       r.TradeType,
+		"product_id":       r.ProductID,
+		"limit_pay":        r.LimitPay,
+		"openid":           r.OpenID,
+		"receipt":          r.Receipt,
+		"scene_info":       r.SceneInfo.String(),
+	})
+}
+
+// MicroPayRequest request of Pay,the auth_code is read from the barcode of user
+type MicroPayRequest struct {
+	DeviceInfo     string
+	Body           string
+	Detail         string
+	Attach         string
+	OutTradeNo     string
+	TotalFee       int
+	FeeType        string
+	SpbillCreateIP string
+	GoodsTag       string
+	LimitPay       string
+	TimeStart      string
+	TimeExpire     string
+	Receipt        string
+	AuthCode       string
+	SceneInfo      *SceneInfo
+}
+
+// Validate ...
+func (r *MicroPayRequest) Validate() error {
+	v := &fieldValidator{}
+	v.length("device_info", r.DeviceInfo, false, 32)
+	v.length("body", r.Body, true, 128)
+	v.length("detail", r.Detail, false, 6000)
+	v.length("attach", r.Attach, false, 127)
+	v.outTradeNo("out_trade_no", r.OutTradeNo)
+	v.fee("total_fee", r.TotalFee)
+	v.length("fee_type", r.FeeType, false, 16)
+	v.length("spbill_create_ip", r.SpbillCreateIP, true, 64)
+	v.length("goods_tag", r.GoodsTag, false, 32)
+	v.times(r.TimeStart, r.TimeExpire)
+	v.length("auth_code", r.AuthCode, true, 128)
+	v.check(r.AuthCode == "" || authCodeRegexp.MatchString(r.AuthCode), "auth_code", "must be 18 digits start with 10-15")
+	v.length("scene_info", r.SceneInfo.String(), false, 256)
+	return v.err()
+}
+
+// ToMap ...
+func (r *MicroPayRequest) ToMap() util.Map {
+	m := util.Map{
+		"total_fee": strconv.Itoa(r.TotalFee),
+	}
+	return setNotEmpty(m, map[string]string{
+		"device_info":      r.DeviceInfo,
+		"body":             r.Body,
+		"detail":           r.Detail,
+		"attach":           r.Attach,
+		"out_trade_no":     r.OutTradeNo,
+		"fee_type":         r.FeeType,
+		"spbill_create_ip": r.SpbillCreateIP,
+		"goods_tag":        r.GoodsTag,
+		"limit_pay":        r.LimitPay,
+		"time_start":       r.TimeStart,
+		"time_expire":      r.TimeExpire,
+		"receipt":          r.Receipt,
+		"auth_code":        r.AuthCode,
+		"scene_info":       r.SceneInfo.String(),
+	})
+}
+
+// RefundRequest request of Refund,one of TransactionID and OutTradeNo is required
+type RefundRequest struct {
+	TransactionID string
+	OutTradeNo    string
+	OutRefundNo   string
+	TotalFee      int
+	RefundFee     int
+	RefundFeeType string
+	RefundDesc    string
+	RefundAccount string
+	NotifyURL     string
+}
+
+// Validate ...
+func (r *RefundRequest) Validate() error {
+	v := &fieldValidator{}
+	v.check(r.TransactionID != "" || r.OutTradeNo != "", "out_trade_no", "one of transaction_id and out_trade_no is required")
+	v.length("transaction_id", r.TransactionID, false, 32)
+	if r.OutTradeNo != "" {
+		v.outTradeNo("out_trade_no", r.OutTradeNo)
+	}
+	v.outRefundNo("out_refund_no", r.OutRefundNo)
+	v.fee("total_fee", r.TotalFee)
+	v.fee("refund_fee", r.RefundFee)
+	v.check(r.RefundFee <= r.TotalFee, "refund_fee", "more than total_fee")
+	v.length("refund_fee_type", r.RefundFeeType, false, 8)
+	v.length("refund_desc", r.RefundDesc, false, 80)
+	v.length("refund_account", r.RefundAccount, false, 30)
+	v.length("notify_url", r.NotifyURL, false, 256)
+	return v.err()
+}
+
+// ToMap ...
+func (r *RefundRequest) ToMap() util.Map {
+	m := util.Map{
+		"total_fee":  strconv.Itoa(r.TotalFee),
+		"refund_fee": strconv.Itoa(r.RefundFee),
+	}
+	return setNotEmpty(m, map[string]string{
+		"transaction_id":  r.TransactionID,
+		"out_trade_no":    r.OutTradeNo,
+		"out_refund_no":   r.OutRefundNo,
+		"refund_fee_type": r.RefundFeeType,
+		"refund_desc":     r.RefundDesc,
+		"refund_account":  r.RefundAccount,
+		"notify_url":      r.NotifyURL,
+	})
+}
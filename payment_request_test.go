@@ -0,0 +1,64 @@
+package wego
+
+import (
+	"testing"
+
+	"golang.org/x/xerrors"
+)
+
+// TestUnifiedOrderRequest_Validate ...
+func TestUnifiedOrderRequest_Validate(t *testing.T) {
+	req := &UnifiedOrderRequest{
+		Body:           "Ipad mini 16G",
+		OutTradeNo:     "20150806125346",
+		TotalFee:       888,
+		SpbillCreateIP: "8.8.8.8",
+		TradeType:      TradeTypeJSAPI,
+		OpenID:         "oUpF8uMuAJO_M2pxb1Q9zNjWeS6o",
+		TimeStart:      "20091225091010",
+		TimeExpire:     "20091227091010",
+		SceneInfo:      &SceneInfo{StoreInfo: &StoreInfo{ID: "SZTX001"}},
+	}
+	if e := req.Validate(); e != nil {
+		t.Fatal(e)
+	}
+	if req.ToMap().GetString("scene_info") != `{"store_info":{"id":"SZTX001"}}` {
+		t.Error(req.ToMap().GetString("scene_info"))
+	}
+
+	req.OutTradeNo = "2015#0806"
+	req.OpenID = ""
+	req.TimeExpire = "2009-12-27"
+	var ve *ValidationError
+	if e := req.Validate(); !xerrors.As(e, &ve) {
+		t.Fatal(e)
+	}
+	for _, field := range []string{"out_trade_no", "openid", "time_expire"} {
+		if ve.Field(field) == nil {
+			t.Errorf("%s should be invalid:%v", field, ve)
+		}
+	}
+	if len(ve.Fields) != 3 {
+		t.Error(ve)
+	}
+
+	//validated before sent
+	payment := NewPayment(&PaymentProperty{AppID: "wx", MchID: "1900000109"})
+	var pe *ValidationError
+	if e := payment.Pay(&MicroPayRequest{AuthCode: "990061098828009406"}).Error(); !xerrors.As(e, &pe) || pe.Field("auth_code") == nil {
+		t.Error(e)
+	}
+}
+
+// TestRefundRequest_Validate ...
+func TestRefundRequest_Validate(t *testing.T) {
+	req := &RefundRequest{OutTradeNo: "1217752501201407033233368018", OutRefundNo: "refund@1217752501201407033233368018", TotalFee: 100, RefundFee: 100}
+	if e := req.Validate(); e != nil {
+		t.Fatal(e)
+	}
+	req.OutRefundNo = "refund#1"
+	var ve *ValidationError
+	if e := req.Validate(); !xerrors.As(e, &ve) || ve.Field("out_refund_no") == nil {
+		t.Error(e)
+	}
+}
@@ -0,0 +1,227 @@
+package wego
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/godcong/wego/cache"
+	"github.com/godcong/wego/cipher"
+	"github.com/godcong/wego/util"
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+// PaymentV3Schema the authorization schema of payment api v3
+const PaymentV3Schema = "WECHATPAY2-SHA256-RSA2048"
+
+const paymentV3UserAgent = "godcong/wego"
+
+// PaymentV3 client of WeChat Pay API v3,the json requests are signed with the merchant private key.
+// the private key and certificate are the Key and Cert of SafeCertProperty
+type PaymentV3 struct {
+	*PaymentProperty
+	client        *Client
+	clientOptions []ClientOption
+	remoteURL     string
+	serialNo      string
+	apiV3Key      string
+	once          sync.Once
+	privateKey    *rsa.PrivateKey
+	keyErr        error
+	cache         cache.Cache
+	cachePrefix   string
+	mu            sync.RWMutex
+	certificates  map[string]*x509.Certificate
+	certFlight    flightGroup
+	downloadedAt  time.Time
+}
+
+// NewPaymentV3 ...
+func NewPaymentV3(property *PaymentProperty, options ...PaymentV3Option) *PaymentV3 {
+	payment := &PaymentV3{
+		PaymentProperty: property,
+	}
+	for _, o := range options {
+		o(payment)
+	}
+	return payment
+}
+
+// RemoteURL ...
+func (obj *PaymentV3) RemoteURL() string {
+	if obj.remoteURL != "" {
+		return obj.remoteURL
+	}
+	return APIMCHDefault
+}
+
+// Client the json client which sign every request
+func (obj *PaymentV3) Client() *Client {
+	if obj.client == nil {
+		options := append([]ClientOption{ClientBodyType(BodyTypeJSON)}, obj.clientOptions...)
+		obj.client = NewClient(append(options, ClientMiddleware(obj.signMiddleware))...)
+	}
+	return obj.client
+}
+
+// SerialNo the serial number of merchant certificate
+func (obj *PaymentV3) SerialNo() (string, error) {
+	if e := obj.loadKey(); e != nil {
+		return "", e
+	}
+	return obj.serialNo, nil
+}
+
+// loadKey parse the private key and the serial number from SafeCert once
+func (obj *PaymentV3) loadKey() error {
+	obj.once.Do(func() {
+		if obj.PaymentProperty == nil || obj.SafeCert == nil {
+			obj.keyErr = xerrors.New("payment v3 need the SafeCert")
+			return
+		}
+		obj.privateKey, obj.keyErr = cipher.ParseRSAPrivateKeyFromPEM(obj.SafeCert.Key)
+		if obj.keyErr != nil || obj.serialNo != "" {
+			return
+		}
+		obj.serialNo, obj.keyErr = certSerialNo(obj.SafeCert.Cert)
+	})
+	return obj.keyErr
+}
+
+// certSerialNo the upper hex serial number of pem certificate
+func certSerialNo(cert []byte) (string, error) {
+	block, _ := pem.Decode(cert)
+	if block == nil {
+		return "", cipher.ErrorKeyMustBePEMEncoded
+	}
+	c, e := x509.ParseCertificate(block.Bytes)
+	if e != nil {
+		return "", e
+	}
+	return fmt.Sprintf("%X", c.SerialNumber), nil
+}
+
+// Do send the request to uri,body is marshaled to json
+func (obj *PaymentV3) Do(ctx context.Context, method, uri string, query util.Map, body interface{}) Responder {
+	content := &RequestContent{
+		Method: method,
+		URL:    util.URL(obj.RemoteURL(), uri),
+		Query:  query,
+	}
+	if body != nil {
+		content.Body = buildBody(body, BodyTypeJSON)
+	}
+	return obj.Client().do(ctx, content)
+}
+
+// Get ...
+func (obj *PaymentV3) Get(ctx context.Context, uri string, query util.Map) Responder {
+	return obj.Do(ctx, http.MethodGet, uri, query, nil)
+}
+
+// Post ...
+func (obj *PaymentV3) Post(ctx context.Context, uri string, body interface{}) Responder {
+	return obj.Do(ctx, http.MethodPost, uri, nil, body)
+}
+
+// signMiddleware set the Authorization header and verify the signature of response,
+// the error body of v3 is returned as *APIError
+func (obj *PaymentV3) signMiddleware(next RoundTrip) RoundTrip {
+	return func(ctx context.Context, content *RequestContent) Responder {
+		auth, e := obj.authorization(content)
+		if e != nil {
+			return ErrResponder(xerrors.Errorf("sign request: %w", e))
+		}
+		if content.Header == nil {
+			content.Header = make(http.Header)
+		}
+		content.Header.Set("Authorization", auth)
+		content.Header.Set("Accept", "application/json")
+		content.Header.Set("User-Agent", paymentV3UserAgent)
+		resp := next(ctx, content)
+		if e := resp.Error(); e != nil {
+			if ae := v3APIError(e); ae != e {
+				return ErrResponder(ae)
+			}
+			return resp
+		}
+		if isV3Certificates(content) {
+			return resp
+		}
+		if e := obj.VerifySignature(ctx, responseHeader(resp), resp.Bytes()); e != nil {
+			return ErrResponder(xerrors.Errorf("verify response: %w", e))
+		}
+		return resp
+	}
+}
+
+// authorization build the value of Authorization header
+func (obj *PaymentV3) authorization(content *RequestContent) (string, error) {
+	if e := obj.loadKey(); e != nil {
+		return "", e
+	}
+	body, e := v3Body(content)
+	if e != nil {
+		return "", e
+	}
+	u, e := url.Parse(content.URLQuery())
+	if e != nil {
+		return "", e
+	}
+	nonce := util.GenerateNonceStr()
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	signature, e := signSHA256WithRSA(obj.privateKey, v3Message(content.Method, u.RequestURI(), timestamp, nonce, string(body)))
+	if e != nil {
+		return "", e
+	}
+	return fmt.Sprintf(`%s mchid="%s",nonce_str="%s",signature="%s",timestamp="%s",serial_no="%s"`,
+		PaymentV3Schema, obj.MchID, nonce, signature, timestamp, obj.serialNo), nil
+}
+
+// v3Body marshal the body once,so the signed bytes are the same as sent
+func v3Body(content *RequestContent) ([]byte, error) {
+	if content.Body == nil || content.Body.BodyInstance == nil {
+		return nil, nil
+	}
+	var body []byte
+	switch v := content.Body.BodyInstance.(type) {
+	case []byte:
+		body = v
+	case string:
+		body = []byte(v)
+	case util.Map:
+		body = v.ToJSON()
+	default:
+		b, e := jsoniter.Marshal(v)
+		if e != nil {
+			return nil, e
+		}
+		body = b
+	}
+	content.Body.BodyInstance = body
+	return body, nil
+}
+
+// v3Message each line end with \n
+func v3Message(lines ...string) []byte {
+	return []byte(strings.Join(lines, "\n") + "\n")
+}
+
+// signSHA256WithRSA base64 encoded SHA256withRSA signature
+func signSHA256WithRSA(key *rsa.PrivateKey, msg []byte) (string, error) {
+	sign, e := cipher.SignSHA256WithRSA(key, msg)
+	if e != nil {
+		return "", e
+	}
+	return base64.StdEncoding.EncodeToString(sign), nil
+}
@@ -0,0 +1,266 @@
+package wego
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/godcong/wego/cipher"
+	"golang.org/x/xerrors"
+)
+
+/*header of payment api v3 response and notify */
+const (
+	HeaderWechatpaySignature = "Wechatpay-Signature"
+	HeaderWechatpayTimestamp = "Wechatpay-Timestamp"
+	HeaderWechatpayNonce     = "Wechatpay-Nonce"
+	HeaderWechatpaySerial    = "Wechatpay-Serial"
+)
+
+// PaymentV3SignatureExpire the response signed before this duration is stale
+const PaymentV3SignatureExpire = 5 * time.Minute
+
+// paymentV3CertificateLife refresh the certificates every 12 hours
+const paymentV3CertificateLife = 12 * 3600
+
+// paymentV3DownloadInterval the unknown serial do not download the certificates again in this interval
+const paymentV3DownloadInterval = time.Minute
+
+// ErrV3Signature the response or notify was not signed by wechat
+var ErrV3Signature = xerrors.New("wechatpay signature verify failed")
+
+// ErrV3CertificateNotFound the platform certificate of serial was not found
+var ErrV3CertificateNotFound = xerrors.New("wechatpay platform certificate not found")
+
+// PlatformCertificate the certificate of wechatpay platform
+type PlatformCertificate struct {
+	SerialNo      string
+	EffectiveTime time.Time
+	ExpireTime    time.Time
+	Certificate   *x509.Certificate
+	PEM           []byte
+}
+
+// EncryptResource the encrypted resource of certificates and notifies
+type EncryptResource struct {
+	Algorithm      string `json:"algorithm"`
+	Nonce          string `json:"nonce"`
+	AssociatedData string `json:"associated_data"`
+	Ciphertext     string `json:"ciphertext"`
+	OriginalType   string `json:"original_type,omitempty"`
+}
+
+type v3CertificatesResponse struct {
+	Data []struct {
+		SerialNo           string           `json:"serial_no"`
+		EffectiveTime      time.Time        `json:"effective_time"`
+		ExpireTime         time.Time        `json:"expire_time"`
+		EncryptCertificate *EncryptResource `json:"encrypt_certificate"`
+	} `json:"data"`
+}
+
+func (obj *PaymentV3) certificateCacheKey(serial string) string {
+	return obj.cachePrefix + "godcong.wego.payment.v3.certificate." + obj.MchID + "." + serial
+}
+
+// DownloadCertificates download the platform certificates,verify the response with them and cache them
+func (obj *PaymentV3) DownloadCertificates(ctx context.Context) ([]*PlatformCertificate, error) {
+	if obj.apiV3Key == "" {
+		return nil, xerrors.New("payment v3 need the api v3 key to decrypt certificates")
+	}
+	resp := obj.Get(ctx, v3Certificates, nil)
+	if e := resp.Error(); e != nil {
+		return nil, e
+	}
+	var data v3CertificatesResponse
+	if e := resp.Unmarshal(&data); e != nil {
+		return nil, e
+	}
+	var certs []*PlatformCertificate
+	for _, d := range data.Data {
+		if d.EncryptCertificate == nil {
+			continue
+		}
+		plain, e := d.EncryptCertificate.Decrypt(obj.apiV3Key)
+		if e != nil {
+			return nil, xerrors.Errorf("decrypt certificate %s: %w", d.SerialNo, e)
+		}
+		cert, e := parseCertificatePEM(plain)
+		if e != nil {
+			return nil, xerrors.Errorf("parse certificate %s: %w", d.SerialNo, e)
+		}
+		certs = append(certs, &PlatformCertificate{
+			SerialNo:      d.SerialNo,
+			EffectiveTime: d.EffectiveTime,
+			ExpireTime:    d.ExpireTime,
+			Certificate:   cert,
+			PEM:           plain,
+		})
+	}
+
+	//the response of certificates is signed by one of them
+	header := responseHeader(resp)
+	for _, c := range certs {
+		if c.SerialNo == header.Get(HeaderWechatpaySerial) {
+			if e := verifyV3Signature(c.Certificate, header, resp.Bytes()); e != nil {
+				return nil, e
+			}
+			obj.storeCertificates(certs)
+			return certs, nil
+		}
+	}
+	return nil, ErrV3Signature
+}
+
+func (obj *PaymentV3) storeCertificates(certs []*PlatformCertificate) {
+	c := useCache(obj.cache)
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if obj.certificates == nil {
+		obj.certificates = make(map[string]*x509.Certificate)
+	}
+	for _, cert := range certs {
+		obj.certificates[cert.SerialNo] = cert.Certificate
+		if ttl := int64(time.Until(cert.ExpireTime) / time.Second); ttl > 0 {
+			c.SetWithTTL(obj.certificateCacheKey(cert.SerialNo), string(cert.PEM), ttl)
+		}
+	}
+}
+
+// Certificate get the platform certificate by serial number,download them when not found,
+// but not more than once in paymentV3DownloadInterval
+func (obj *PaymentV3) Certificate(ctx context.Context, serial string) (*x509.Certificate, error) {
+	if cert := obj.loadCertificate(serial); cert != nil {
+		return cert, nil
+	}
+	_, e := obj.certFlight.Do("certificates", func() (string, error) {
+		if obj.loadCertificate(serial) != nil || !obj.downloadable() {
+			return "", nil
+		}
+		_, e := obj.DownloadCertificates(ctx)
+		return "", e
+	})
+	if e != nil {
+		return nil, e
+	}
+	if cert := obj.loadCertificate(serial); cert != nil {
+		return cert, nil
+	}
+	return nil, ErrV3CertificateNotFound
+}
+
+// downloadable the last download was not in paymentV3DownloadInterval
+func (obj *PaymentV3) downloadable() bool {
+	obj.mu.Lock()
+	defer obj.mu.Unlock()
+	if time.Since(obj.downloadedAt) < paymentV3DownloadInterval {
+		return false
+	}
+	obj.downloadedAt = time.Now()
+	return true
+}
+
+// loadCertificate get the not expired certificate in memory or cache
+func (obj *PaymentV3) loadCertificate(serial string) *x509.Certificate {
+	obj.mu.RLock()
+	cert := obj.certificates[serial]
+	obj.mu.RUnlock()
+	if cert != nil && time.Now().Before(cert.NotAfter) {
+		return cert
+	}
+	v, b := useCache(obj.cache).Get(obj.certificateCacheKey(serial)).(string)
+	if !b || v == "" {
+		return nil
+	}
+	cert, e := parseCertificatePEM([]byte(v))
+	if e != nil {
+		log.Error(e)
+		return nil
+	}
+	if !time.Now().Before(cert.NotAfter) {
+		return nil
+	}
+	obj.mu.Lock()
+	if obj.certificates == nil {
+		obj.certificates = make(map[string]*x509.Certificate)
+	}
+	obj.certificates[serial] = cert
+	obj.mu.Unlock()
+	return cert
+}
+
+// VerifySignature verify the Wechatpay-* headers of response or notify
+func (obj *PaymentV3) VerifySignature(ctx context.Context, header http.Header, body []byte) error {
+	serial := header.Get(HeaderWechatpaySerial)
+	if serial == "" || header.Get(HeaderWechatpaySignature) == "" {
+		return xerrors.Errorf("unsigned response: %w", ErrV3Signature)
+	}
+	cert, e := obj.Certificate(ctx, serial)
+	if e != nil {
+		return e
+	}
+	return verifyV3Signature(cert, header, body)
+}
+
+// verifyV3Signature message is timestamp\nnonce\nbody\n
+func verifyV3Signature(cert *x509.Certificate, header http.Header, body []byte) error {
+	ts, e := strconv.ParseInt(header.Get(HeaderWechatpayTimestamp), 10, 64)
+	if e != nil {
+		return xerrors.Errorf("wrong timestamp: %w", ErrV3Signature)
+	}
+	if d := time.Since(time.Unix(ts, 0)); d > PaymentV3SignatureExpire || d < -PaymentV3SignatureExpire {
+		return xerrors.Errorf("stale response: %w", ErrV3Signature)
+	}
+	key, b := cert.PublicKey.(*rsa.PublicKey)
+	if !b {
+		return xerrors.Errorf("certificate is not rsa: %w", ErrV3Signature)
+	}
+	sign, e := base64.StdEncoding.DecodeString(header.Get(HeaderWechatpaySignature))
+	if e != nil {
+		return xerrors.Errorf("wrong signature: %w", ErrV3Signature)
+	}
+	msg := v3Message(header.Get(HeaderWechatpayTimestamp), header.Get(HeaderWechatpayNonce), string(body))
+	if e := cipher.VerifySHA256WithRSA(key, msg, sign); e != nil {
+		return ErrV3Signature
+	}
+	return nil
+}
+
+// responseHeader the header of response,empty when the responder was not built from http
+func responseHeader(resp Responder) http.Header {
+	if r, b := resp.(interface{ Header() http.Header }); b && r.Header() != nil {
+		return r.Header()
+	}
+	return http.Header{}
+}
+
+// Decrypt decrypt the resource with api v3 key
+func (r *EncryptResource) Decrypt(key string) ([]byte, error) {
+	if r.Algorithm != "" && r.Algorithm != "AEAD_AES_256_GCM" {
+		return nil, xerrors.Errorf("unsupported algorithm:%s", r.Algorithm)
+	}
+	return cipher.New(cipher.AES256GCM, cipher.OptionKey(key)).Decrypt(&cipher.GCMData{
+		Ciphertext:     r.Ciphertext,
+		Nonce:          r.Nonce,
+		AssociatedData: r.AssociatedData,
+	})
+}
+
+func parseCertificatePEM(b []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, xerrors.New("certificate must be pem encoded")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// isV3Certificates the download of certificates is verified by itself
+func isV3Certificates(content *RequestContent) bool {
+	return strings.HasSuffix(content.URL, v3Certificates)
+}
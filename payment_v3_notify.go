@@ -0,0 +1,190 @@
+package wego
+
+import (
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+
+	jsoniter "github.com/json-iterator/go"
+	"golang.org/x/xerrors"
+)
+
+// V3NotifyResult the answer of payment api v3 notify
+type V3NotifyResult struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// V3Notify the notify of payment api v3,the Resource is encrypted with api v3 key
+type V3Notify struct {
+	ID           string           `json:"id"`
+	CreateTime   time.Time        `json:"create_time"`
+	EventType    string           `json:"event_type"`
+	ResourceType string           `json:"resource_type"`
+	Summary      string           `json:"summary"`
+	Resource     *EncryptResource `json:"resource"`
+}
+
+// V3Transaction the decrypted resource of TRANSACTION.SUCCESS
+type V3Transaction struct {
+	AppID          string `json:"appid"`
+	MchID          string `json:"mchid"`
+	OutTradeNo     string `json:"out_trade_no"`
+	TransactionID  string `json:"transaction_id"`
+	TradeType      string `json:"trade_type"`
+	TradeState     string `json:"trade_state"`
+	TradeStateDesc string `json:"trade_state_desc"`
+	BankType       string `json:"bank_type"`
+	Attach         string `json:"attach"`
+	SuccessTime    string `json:"success_time"`
+	Payer          struct {
+		OpenID string `json:"openid"`
+	} `json:"payer"`
+	Amount struct {
+		Total         int64  `json:"total"`
+		PayerTotal    int64  `json:"payer_total"`
+		Currency      string `json:"currency"`
+		PayerCurrency string `json:"payer_currency"`
+	} `json:"amount"`
+	SceneInfo struct {
+		DeviceID string `json:"device_id"`
+	} `json:"scene_info"`
+}
+
+// V3Refund the decrypted resource of REFUND.SUCCESS,REFUND.ABNORMAL and REFUND.CLOSED
+type V3Refund struct {
+	MchID               string `json:"mchid"`
+	OutTradeNo          string `json:"out_trade_no"`
+	TransactionID       string `json:"transaction_id"`
+	OutRefundNo         string `json:"out_refund_no"`
+	RefundID            string `json:"refund_id"`
+	RefundStatus        string `json:"refund_status"`
+	SuccessTime         string `json:"success_time"`
+	UserReceivedAccount string `json:"user_received_account"`
+	Amount              struct {
+		Total       int64 `json:"total"`
+		Refund      int64 `json:"refund"`
+		PayerTotal  int64 `json:"payer_total"`
+		PayerRefund int64 `json:"payer_refund"`
+	} `json:"amount"`
+}
+
+// V3PaidHook return error to make wechat notify again
+type V3PaidHook func(notify *V3Notify, transaction *V3Transaction) error
+
+// V3RefundedHook return error to make wechat notify again
+type V3RefundedHook func(notify *V3Notify, refund *V3Refund) error
+
+// paymentV3Notify verify and decrypt the notify of payment api v3,
+// the hook is called with the notify whose event_type has the prefix of eventType
+type paymentV3Notify struct {
+	*PaymentV3
+	eventType string
+	hook      func(notify *V3Notify, plain []byte) error
+}
+
+// ServeHTTP the failed notify is answered with non 2xx status,so wechat will notify again
+func (n *paymentV3Notify) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, e := ioutil.ReadAll(req.Body)
+	if e != nil {
+		log.Error(e)
+		writeV3NotifyResult(w, http.StatusBadRequest, e)
+		return
+	}
+	if e := n.VerifySignature(req.Context(), req.Header, body); e != nil {
+		log.Error(e)
+		writeV3NotifyResult(w, http.StatusUnauthorized, e)
+		return
+	}
+	var notify V3Notify
+	if e := jsoniter.Unmarshal(body, &notify); e != nil || notify.Resource == nil {
+		log.Error(e)
+		writeV3NotifyResult(w, http.StatusBadRequest, xerrors.New("wrong notify resource"))
+		return
+	}
+	if !strings.HasPrefix(notify.EventType, n.eventType) {
+		log.Error("wrong event type:", notify.EventType)
+		writeV3NotifyResult(w, http.StatusBadRequest, xerrors.Errorf("wrong event type: %s", notify.EventType))
+		return
+	}
+	plain, e := notify.Resource.Decrypt(n.apiV3Key)
+	if e != nil {
+		log.Error(e)
+		writeV3NotifyResult(w, http.StatusBadRequest, xerrors.Errorf("decrypt resource: %w", e))
+		return
+	}
+	if n.hook == nil {
+		e = xerrors.New("null notify callback")
+	} else {
+		e = n.hook(&notify, plain)
+	}
+	if e != nil {
+		log.Error(e)
+		writeV3NotifyResult(w, http.StatusInternalServerError, e)
+		return
+	}
+	writeV3NotifyResult(w, http.StatusOK, nil)
+}
+
+// writeV3NotifyResult answer SUCCESS when e is nil
+func writeV3NotifyResult(w http.ResponseWriter, status int, e error) {
+	result := &V3NotifyResult{
+		Code:    "SUCCESS",
+		Message: "OK",
+	}
+	if e != nil {
+		result.Code = "FAIL"
+		result.Message = e.Error()
+	}
+	bytes, e := jsoniter.Marshal(result)
+	if e != nil {
+		log.Error(e)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	if _, e := w.Write(bytes); e != nil {
+		log.Error(e)
+	}
+}
+
+// HandlePaidNotify the hook is called with TRANSACTION.SUCCESS only
+func (obj *PaymentV3) HandlePaidNotify(hook V3PaidHook) Notifier {
+	notify := &paymentV3Notify{PaymentV3: obj, eventType: "TRANSACTION.SUCCESS"}
+	if hook != nil {
+		notify.hook = func(n *V3Notify, plain []byte) error {
+			var transaction V3Transaction
+			if e := jsoniter.Unmarshal(plain, &transaction); e != nil {
+				return e
+			}
+			return hook(n, &transaction)
+		}
+	}
+	return notify
+}
+
+// HandlePaid ...
+func (obj *PaymentV3) HandlePaid(hook V3PaidHook) ServeHTTPFunc {
+	return obj.HandlePaidNotify(hook).ServeHTTP
+}
+
+// HandleRefundedNotify the hook is called with REFUND.SUCCESS,REFUND.ABNORMAL and REFUND.CLOSED
+func (obj *PaymentV3) HandleRefundedNotify(hook V3RefundedHook) Notifier {
+	notify := &paymentV3Notify{PaymentV3: obj, eventType: "REFUND."}
+	if hook != nil {
+		notify.hook = func(n *V3Notify, plain []byte) error {
+			var refund V3Refund
+			if e := jsoniter.Unmarshal(plain, &refund); e != nil {
+				return e
+			}
+			return hook(n, &refund)
+		}
+	}
+	return notify
+}
+
+// HandleRefunded ...
+func (obj *PaymentV3) HandleRefunded(hook V3RefundedHook) ServeHTTPFunc {
+	return obj.HandleRefundedNotify(hook).ServeHTTP
+}
@@ -0,0 +1,322 @@
+package wego
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godcong/wego/cache"
+	"github.com/godcong/wego/cipher"
+	"github.com/godcong/wego/util"
+	"golang.org/x/xerrors"
+)
+
+// testV3Cert generate a key and certificate with serial number
+func testV3Cert(t *testing.T, serial int64) (*rsa.PrivateKey, *SafeCertProperty) {
+	key, e := rsa.GenerateKey(rand.Reader, 2048)
+	if e != nil {
+		t.Fatal(e)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "mch"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, e := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	pkcs8, e := x509.MarshalPKCS8PrivateKey(key)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return key, &SafeCertProperty{
+		Cert: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		Key:  pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}),
+	}
+}
+
+const testV3APIKey = "01234567890123456789012345678901"
+
+var v3AuthRegexp = regexp.MustCompile(`^WECHATPAY2-SHA256-RSA2048 mchid="(.*)",nonce_str="(.*)",signature="(.*)",timestamp="(.*)",serial_no="(.*)"$`)
+
+// testV3Platform sign the responses and serve the certificates like wechatpay
+type testV3Platform struct {
+	key  *rsa.PrivateKey
+	cert *SafeCertProperty
+}
+
+func newTestV3Platform(t *testing.T) *testV3Platform {
+	key, cert := testV3Cert(t, 0x5157)
+	return &testV3Platform{key: key, cert: cert}
+}
+
+func (p *testV3Platform) sign(header http.Header, timestamp int64, body []byte) {
+	nonce := util.GenerateNonceStr()
+	ts := strconv.FormatInt(timestamp, 10)
+	sign, _ := signSHA256WithRSA(p.key, v3Message(ts, nonce, string(body)))
+	header.Set(HeaderWechatpaySerial, "5157")
+	header.Set(HeaderWechatpayTimestamp, ts)
+	header.Set(HeaderWechatpayNonce, nonce)
+	header.Set(HeaderWechatpaySignature, sign)
+}
+
+func (p *testV3Platform) write(w http.ResponseWriter, timestamp int64, body []byte) {
+	p.sign(w.Header(), timestamp, body)
+	_, _ = w.Write(body)
+}
+
+func (p *testV3Platform) certificates() []byte {
+	block, _ := aes.NewCipher([]byte(testV3APIKey))
+	gcm, _ := gocipher.NewGCM(block)
+	nonce := "0123456789ab"
+	ciphertext := gcm.Seal(nil, []byte(nonce), p.cert.Cert, []byte("certificate"))
+	return util.Map{
+		"data": []util.Map{{
+			"serial_no":      "5157",
+			"effective_time": time.Now().Add(-time.Hour).Format(time.RFC3339),
+			"expire_time":    time.Now().Add(time.Hour).Format(time.RFC3339),
+			"encrypt_certificate": util.Map{
+				"algorithm":       "AEAD_AES_256_GCM",
+				"nonce":           nonce,
+				"associated_data": "certificate",
+				"ciphertext":      base64.StdEncoding.EncodeToString(ciphertext),
+			},
+		}},
+	}.ToJSON()
+}
+
+// notify post the notify body to handler,signed by the platform
+func (p *testV3Platform) notify(handler ServeHTTPFunc, body []byte, signed bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/notify", bytes.NewReader(body))
+	if signed {
+		p.sign(req.Header, time.Now().Unix(), body)
+	}
+	w := httptest.NewRecorder()
+	handler(w, req)
+	return w
+}
+
+// testV3NotifyBody the notify with resource encrypted by api v3 key
+func testV3NotifyBody(eventType, associatedData, text string) []byte {
+	ciphertext, _ := cipher.New(cipher.AES256GCM, cipher.OptionKey(testV3APIKey)).Encrypt(&cipher.GCMData{
+		Text:           text,
+		Nonce:          "fdasflkja484w",
+		AssociatedData: associatedData,
+	})
+	return util.Map{
+		"id":            "EV-2018022511223320873",
+		"event_type":    eventType,
+		"resource_type": "encrypt-resource",
+		"resource": util.Map{
+			"algorithm":       "AEAD_AES_256_GCM",
+			"ciphertext":      string(ciphertext),
+			"nonce":           "fdasflkja484w",
+			"associated_data": associatedData,
+		},
+	}.ToJSON()
+}
+
+// TestPaymentV3_Certificate ...
+func TestPaymentV3_Certificate(t *testing.T) {
+	_, cert := testV3Cert(t, 0x1A2B)
+	platform := newTestV3Platform(t)
+	var downloads int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&downloads, 1)
+		time.Sleep(20 * time.Millisecond)
+		platform.write(w, time.Now().Unix(), platform.certificates())
+	}))
+	defer server.Close()
+	newPayment := func(c cache.Cache) *PaymentV3 {
+		return NewPaymentV3(&PaymentProperty{MchID: "1900000001", SafeCert: cert},
+			PaymentV3Remote(server.URL), PaymentV3APIKey(testV3APIKey), PaymentV3Cache(c))
+	}
+
+	//the instances with same mch id but different caches do not share the download
+	p1, p2 := newPayment(cache.NewMapCache()), newPayment(cache.NewMapCache())
+	var wg sync.WaitGroup
+	for _, p := range []*PaymentV3{p1, p2} {
+		wg.Add(1)
+		go func(p *PaymentV3) {
+			defer wg.Done()
+			if _, e := p.Certificate(context.Background(), "5157"); e != nil {
+				t.Error(e)
+			}
+		}(p)
+	}
+	wg.Wait()
+
+	n := atomic.LoadInt32(&downloads)
+	for i := 0; i < 3; i++ {
+		if _, e := p1.Certificate(context.Background(), "unknown"+strconv.Itoa(i)); e != ErrV3CertificateNotFound {
+			t.Errorf("want not found,got %v", e)
+		}
+	}
+	if d := atomic.LoadInt32(&downloads); d != n {
+		t.Errorf("unknown serial should not download again,got %d downloads", d-n)
+	}
+
+	c := cache.NewMapCache()
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	der, _ := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(0xDEAD),
+		NotBefore:    time.Now().Add(-2 * time.Hour),
+		NotAfter:     time.Now().Add(-time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(0xDEAD)}, &key.PublicKey, key)
+	p3 := newPayment(c)
+	c.Set(p3.certificateCacheKey("DEAD"), string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})))
+	if p3.loadCertificate("DEAD") != nil {
+		t.Error("expired certificate in cache should not be trusted")
+	}
+}
+
+// TestPaymentV3_Sign ...
+func TestPaymentV3_Sign(t *testing.T) {
+	key, cert := testV3Cert(t, 0x1A2B)
+	platform := newTestV3Platform(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		m := v3AuthRegexp.FindStringSubmatch(r.Header.Get("Authorization"))
+		if m == nil || m[1] != "1900000001" || m[5] != "1A2B" {
+			t.Errorf("wrong authorization:%s", r.Header.Get("Authorization"))
+			return
+		}
+		msg := v3Message(r.Method, r.URL.RequestURI(), m[4], m[2], string(body))
+		sign, _ := base64.StdEncoding.DecodeString(m[3])
+		hashed := sha256.Sum256(msg)
+		if e := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hashed[:], sign); e != nil {
+			t.Error(e)
+		}
+		switch r.URL.Path {
+		case v3Certificates:
+			platform.write(w, time.Now().Unix(), platform.certificates())
+		case "/v3/pay/transactions/out-trade-no/closed":
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code":"ORDER_CLOSED","message":"order closed"}`))
+		case "/v3/stale":
+			platform.write(w, time.Now().Add(-time.Hour).Unix(), []byte(`{}`))
+		case "/v3/unsigned":
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			platform.write(w, time.Now().Unix(), []byte(`{"prepay_id":"wx201410272009395522657a690389285100"}`))
+		}
+	}))
+	defer server.Close()
+
+	payment := NewPaymentV3(&PaymentProperty{MchID: "1900000001", SafeCert: cert},
+		PaymentV3Remote(server.URL), PaymentV3APIKey(testV3APIKey), PaymentV3Cache(cache.NewMapCache()))
+	resp := payment.Post(context.Background(), "/v3/pay/transactions/jsapi", util.Map{"appid": "wxd678efh567hg6787"})
+	if e := resp.Error(); e != nil {
+		t.Fatal(e)
+	}
+	if resp.ToMap().GetString("prepay_id") == "" {
+		t.Error("empty prepay_id")
+	}
+	resp = payment.Get(context.Background(), "/v3/pay/transactions/out-trade-no/closed", util.Map{"mchid": "1900000001"})
+	if !IsPayErrCode(resp.Error(), "ORDER_CLOSED") {
+		t.Errorf("want api error,got %v", resp.Error())
+	}
+	for _, uri := range []string{"/v3/stale", "/v3/unsigned"} {
+		if e := payment.Get(context.Background(), uri, nil).Error(); !xerrors.Is(e, ErrV3Signature) {
+			t.Errorf("%s should be rejected,got %v", uri, e)
+		}
+	}
+}
+
+// TestPaymentV3_HandlePaidNotify ...
+func TestPaymentV3_HandlePaidNotify(t *testing.T) {
+	_, cert := testV3Cert(t, 0x1A2B)
+	platform := newTestV3Platform(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		platform.write(w, time.Now().Unix(), platform.certificates())
+	}))
+	defer server.Close()
+	payment := NewPaymentV3(&PaymentProperty{MchID: "1900000001", SafeCert: cert},
+		PaymentV3Remote(server.URL), PaymentV3APIKey(testV3APIKey), PaymentV3Cache(cache.NewMapCache()))
+
+	var hookErr error
+	var paid *V3Transaction
+	handler := payment.HandlePaid(func(notify *V3Notify, transaction *V3Transaction) error {
+		paid = transaction
+		return hookErr
+	})
+	body := testV3NotifyBody("TRANSACTION.SUCCESS", "transaction",
+		`{"mchid":"1900000001","out_trade_no":"1217752501201407033233368018","trade_state":"SUCCESS","amount":{"total":100}}`)
+	notify := func(signed bool) *httptest.ResponseRecorder {
+		return platform.notify(handler, body, signed)
+	}
+
+	if w := notify(true); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "SUCCESS") {
+		t.Fatal(w.Code, w.Body.String())
+	}
+	if paid == nil || paid.OutTradeNo != "1217752501201407033233368018" || paid.Amount.Total != 100 {
+		t.Errorf("wrong transaction:%+v", paid)
+	}
+	hookErr = xerrors.New("order is busy")
+	if w := notify(true); w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "FAIL") {
+		t.Error(w.Code, w.Body.String())
+	}
+	paid = nil
+	if w := notify(false); w.Code != http.StatusUnauthorized || paid != nil {
+		t.Error(w.Code, w.Body.String())
+	}
+
+	body = bytes.Replace(body, []byte("TRANSACTION.SUCCESS"), []byte("REFUND.SUCCESS"), 1)
+	if w := notify(true); w.Code != http.StatusBadRequest || paid != nil {
+		t.Error("refund event should not be decoded as transaction", w.Code, w.Body.String())
+	}
+}
+
+// TestPaymentV3_HandleRefundedNotify ...
+func TestPaymentV3_HandleRefundedNotify(t *testing.T) {
+	_, cert := testV3Cert(t, 0x1A2B)
+	platform := newTestV3Platform(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		platform.write(w, time.Now().Unix(), platform.certificates())
+	}))
+	defer server.Close()
+	payment := NewPaymentV3(&PaymentProperty{MchID: "1900000001", SafeCert: cert},
+		PaymentV3Remote(server.URL), PaymentV3APIKey(testV3APIKey), PaymentV3Cache(cache.NewMapCache()))
+
+	var refunded *V3Refund
+	handler := payment.HandleRefunded(func(notify *V3Notify, refund *V3Refund) error {
+		refunded = refund
+		return nil
+	})
+	body := testV3NotifyBody("REFUND.SUCCESS", "refund",
+		`{"mchid":"1900000001","out_trade_no":"1217752501201407033233368018","out_refund_no":"1217752501201407033233368018","refund_status":"SUCCESS","amount":{"total":100,"refund":100}}`)
+	if w := platform.notify(handler, body, true); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "SUCCESS") {
+		t.Fatal(w.Code, w.Body.String())
+	}
+	if refunded == nil || refunded.OutRefundNo != "1217752501201407033233368018" || refunded.RefundStatus != "SUCCESS" || refunded.Amount.Refund != 100 {
+		t.Errorf("wrong refund:%+v", refunded)
+	}
+
+	refunded = nil
+	body = testV3NotifyBody("TRANSACTION.SUCCESS", "transaction", `{"mchid":"1900000001","out_trade_no":"1217752501201407033233368018"}`)
+	if w := platform.notify(handler, body, true); w.Code != http.StatusBadRequest || refunded != nil {
+		t.Error("transaction event should not be decoded as refund", w.Code, w.Body.String())
+	}
+}
@@ -92,6 +92,8 @@ type AccessTokenProperty struct {
 	GrantType string `toml:"grant_type"`
 	AppID     string `toml:"app_id"`
 	AppSecret string `toml:"app_secret"`
+	// Stable use /cgi-bin/stable_token,the old token is still valid after refresh
+	Stable bool `toml:"stable"`
 }
 
 // ToMap ...
@@ -0,0 +1,282 @@
+package wego
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/godcong/wego/util"
+)
+
+// LocalRefund the refund saved by merchant,the amounts are in fen(分)
+type LocalRefund struct {
+	OutRefundNo string
+	RefundFee   int64
+	// Status SUCCESS,PROCESSING,CHANGE or REFUNDCLOSE
+	Status string
+}
+
+// LocalOrder the order saved by merchant,the amounts are in fen(分)
+type LocalOrder struct {
+	OutTradeNo    string
+	TransactionID string
+	TotalFee      int64
+	// State the trade_state:SUCCESS,REFUND,NOTPAY,CLOSED,REVOKED...
+	State   string
+	Refunds []*LocalRefund
+}
+
+// refund find the refund by out_refund_no
+func (o *LocalOrder) refund(no string) *LocalRefund {
+	for _, r := range o.Refunds {
+		if r.OutRefundNo == no {
+			return r
+		}
+	}
+	return nil
+}
+
+// paid the order was paid,the refunded order was paid too
+func (o *LocalOrder) paid() bool {
+	return o.State == "SUCCESS" || o.State == "REFUND"
+}
+
+// OrderStore the orders of merchant to reconcile with the bill
+type OrderStore interface {
+	// Order find the order by out_trade_no,nil when not found
+	Order(ctx context.Context, outTradeNo string) (*LocalOrder, error)
+	// PaidOrders iterate the orders paid in [begin,end),stop when fn returns error
+	PaidOrders(ctx context.Context, begin, end time.Time, fn func(order *LocalOrder) error) error
+}
+
+// ReconcileKind ...
+type ReconcileKind int
+
+/*kinds of reconcile difference */
+const (
+	// ReconcileMissingLocally in the bill but not in the store
+	ReconcileMissingLocally ReconcileKind = iota
+	// ReconcileMissingRemotely paid in the store but not in the bill
+	ReconcileMissingRemotely
+	// ReconcileAmountMismatch the total fee is different
+	ReconcileAmountMismatch
+	// ReconcileStatusMismatch the order is paid in one side only
+	ReconcileStatusMismatch
+	// ReconcileRefundMismatch the refund is missing or different
+	ReconcileRefundMismatch
+	// ReconcileTransactionMismatch the transaction_id is different
+	ReconcileTransactionMismatch
+)
+
+// String ...
+func (k ReconcileKind) String() string {
+	switch k {
+	case ReconcileMissingLocally:
+		return "MISSING_LOCALLY"
+	case ReconcileMissingRemotely:
+		return "MISSING_REMOTELY"
+	case ReconcileAmountMismatch:
+		return "AMOUNT_MISMATCH"
+	case ReconcileStatusMismatch:
+		return "STATUS_MISMATCH"
+	case ReconcileRefundMismatch:
+		return "REFUND_MISMATCH"
+	}
+	return "TRANSACTION_MISMATCH"
+}
+
+// ReconcileDiff a difference between the store and the bill
+type ReconcileDiff struct {
+	Kind        ReconcileKind
+	OutTradeNo  string
+	OutRefundNo string
+	Local       *LocalOrder
+	Remote      *BillRecord
+	Reason      string
+}
+
+// ReconcileTotals the totals of bill records,the amounts are in fen(分)
+type ReconcileTotals struct {
+	Count              int64
+	SettlementTotalFee int64
+	RefundFee          int64
+	CouponRefundFee    int64
+	ServiceFee         int64
+	TotalFee           int64
+	RefundRequestFee   int64
+}
+
+// ReconcileReport ...
+type ReconcileReport struct {
+	Begin time.Time
+	End   time.Time
+	Diffs []*ReconcileDiff
+	// Totals summed from the records
+	Totals ReconcileTotals
+	// Summary the trailer of bill,nil when the bill has no trailer,empty when the day has no bill
+	Summary *BillSummary
+	// SummaryErrors the totals different from the trailer
+	SummaryErrors []string
+}
+
+// Balanced no difference was found and the totals are same as the trailer
+func (r *ReconcileReport) Balanced() bool {
+	return len(r.Diffs) == 0 && len(r.SummaryErrors) == 0 && r.Summary != nil
+}
+
+func (r *ReconcileReport) diff(kind ReconcileKind, local *LocalOrder, remote *BillRecord, format string, args ...interface{}) {
+	d := &ReconcileDiff{
+		Kind:   kind,
+		Local:  local,
+		Remote: remote,
+		Reason: fmt.Sprintf(format, args...),
+	}
+	if remote != nil {
+		d.OutTradeNo, d.OutRefundNo = remote.OutTradeNo, remote.OutRefundNo
+	} else if local != nil {
+		d.OutTradeNo = local.OutTradeNo
+	}
+	r.Diffs = append(r.Diffs, d)
+}
+
+// Reconcile download the ALL bill of date and reconcile it with the store,
+// the day is from 00:00 to 24:00 of Beijing time.
+// the day without bill(20002 No Bill Exist) is reconciled as an empty bill
+func (obj *Payment) Reconcile(ctx context.Context, date time.Time, store OrderStore) (*ReconcileReport, error) {
+	reader, e := obj.BillReader(ctx, date.In(billLocation).Format("20060102"), util.Map{
+		"bill_type": "ALL",
+		"tar_type":  "GZIP",
+	})
+	if e != nil {
+		if !IsPayErrCode(e, PayErrCodeNoBillExist) {
+			return nil, e
+		}
+		return ReconcileBill(ctx, date, nil, store)
+	}
+	defer reader.Close()
+	return ReconcileBill(ctx, date, reader, store)
+}
+
+// ReconcileBill reconcile the ALL bill of date with the store,the saved bill can be reconciled again with it.
+// the reader is nil when the day has no bill
+func ReconcileBill(ctx context.Context, date time.Time, reader *BillReader, store OrderStore) (*ReconcileReport, error) {
+	y, m, d := date.In(billLocation).Date()
+	report := &ReconcileReport{
+		Begin:   time.Date(y, m, d, 0, 0, 0, 0, billLocation),
+		Summary: &BillSummary{},
+	}
+	report.End = report.Begin.AddDate(0, 0, 1)
+
+	paid := make(map[string]bool)
+	if reader != nil {
+		if e := reconcileRecords(ctx, report, reader, store, paid); e != nil {
+			return nil, e
+		}
+		report.Summary = reader.Summary()
+	}
+
+	e := store.PaidOrders(ctx, report.Begin, report.End, func(order *LocalOrder) error {
+		if !paid[order.OutTradeNo] {
+			report.diff(ReconcileMissingRemotely, order, nil, "local:%s", order.State)
+		}
+		return nil
+	})
+	if e != nil {
+		return nil, e
+	}
+	report.SummaryErrors = report.Totals.check(report.Summary)
+	return report, nil
+}
+
+// reconcileRecords the orders in bill are set to seen,so they are not reported as missing remotely
+func reconcileRecords(ctx context.Context, report *ReconcileReport, reader *BillReader, store OrderStore, seen map[string]bool) error {
+	for reader.Next() {
+		record := reader.Record()
+		report.Totals.add(record)
+		local, e := store.Order(ctx, record.OutTradeNo)
+		if e != nil {
+			return e
+		}
+		if local == nil {
+			report.diff(ReconcileMissingLocally, nil, record, "trade_state:%s", record.TradeState)
+			continue
+		}
+		switch record.TradeState {
+		case "REFUND":
+			reconcileRefund(report, local, record)
+		case "SUCCESS":
+			seen[record.OutTradeNo] = true
+			reconcilePaid(report, local, record)
+		default:
+			if local.paid() {
+				seen[record.OutTradeNo] = true
+				report.diff(ReconcileStatusMismatch, local, record, "local:%s,remote:%s", local.State, record.TradeState)
+			}
+		}
+	}
+	return reader.Err()
+}
+
+func reconcilePaid(report *ReconcileReport, local *LocalOrder, record *BillRecord) {
+	if !local.paid() {
+		report.diff(ReconcileStatusMismatch, local, record, "local:%s,remote:%s", local.State, record.TradeState)
+	}
+	total := record.TotalFee
+	if total == 0 {
+		total = record.SettlementTotalFee + record.CouponFee
+	}
+	if local.TotalFee != total {
+		report.diff(ReconcileAmountMismatch, local, record, "local:%d,remote:%d", local.TotalFee, total)
+	}
+	if local.TransactionID != "" && record.TransactionID != "" && local.TransactionID != record.TransactionID {
+		report.diff(ReconcileTransactionMismatch, local, record, "local:%s,remote:%s", local.TransactionID, record.TransactionID)
+	}
+}
+
+func reconcileRefund(report *ReconcileReport, local *LocalOrder, record *BillRecord) {
+	refund := local.refund(record.OutRefundNo)
+	if refund == nil {
+		report.diff(ReconcileRefundMismatch, local, record, "refund %s not found locally", record.OutRefundNo)
+		return
+	}
+	fee := record.RefundRequestFee
+	if fee == 0 {
+		fee = record.RefundFee
+	}
+	if refund.RefundFee != fee {
+		report.diff(ReconcileRefundMismatch, local, record, "refund fee local:%d,remote:%d", refund.RefundFee, fee)
+	}
+	if record.RefundStatus != "" && refund.Status != record.RefundStatus {
+		report.diff(ReconcileRefundMismatch, local, record, "refund status local:%s,remote:%s", refund.Status, record.RefundStatus)
+	}
+}
+
+func (t *ReconcileTotals) add(record *BillRecord) {
+	t.Count++
+	t.SettlementTotalFee += record.SettlementTotalFee
+	t.RefundFee += record.RefundFee
+	t.CouponRefundFee += record.CouponRefundFee
+	t.ServiceFee += record.ServiceFee
+	t.TotalFee += record.TotalFee
+	t.RefundRequestFee += record.RefundRequestFee
+}
+
+// check the totals with the trailer,the service fee is rounded by wechat so it is not checked
+func (t *ReconcileTotals) check(s *BillSummary) []string {
+	if s == nil {
+		return []string{"bill summary not found"}
+	}
+	var errs []string
+	compare := func(name string, total, summary int64) {
+		if total != summary {
+			errs = append(errs, fmt.Sprintf("%s total:%d,summary:%d", name, total, summary))
+		}
+	}
+	compare("count", t.Count, s.TotalCount)
+	compare("settlement_total_fee", t.SettlementTotalFee, s.SettlementTotalFee)
+	compare("refund_fee", t.RefundFee, s.RefundFee)
+	compare("coupon_refund_fee", t.CouponRefundFee, s.CouponRefundFee)
+	compare("total_fee", t.TotalFee, s.TotalFee)
+	compare("refund_request_fee", t.RefundRequestFee, s.RefundRequestFee)
+	return errs
+}
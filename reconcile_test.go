@@ -0,0 +1,157 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testOrderStore struct {
+	orders map[string]*LocalOrder
+	paidAt map[string]time.Time
+}
+
+func (s *testOrderStore) Order(ctx context.Context, outTradeNo string) (*LocalOrder, error) {
+	return s.orders[outTradeNo], nil
+}
+
+func (s *testOrderStore) PaidOrders(ctx context.Context, begin, end time.Time, fn func(order *LocalOrder) error) error {
+	for no, t := range s.paidAt {
+		if !t.Before(begin) && t.Before(end) {
+			if e := fn(s.orders[no]); e != nil {
+				return e
+			}
+		}
+	}
+	return nil
+}
+
+func testReconcile(t *testing.T, store OrderStore) *ReconcileReport {
+	reader, e := NewBillReader(strings.NewReader(testBill))
+	if e != nil {
+		t.Fatal(e)
+	}
+	defer reader.Close()
+	report, e := ReconcileBill(context.Background(), time.Date(2014, 11, 10, 12, 0, 0, 0, billLocation), reader, store)
+	if e != nil {
+		t.Fatal(e)
+	}
+	return report
+}
+
+// TestReconcileBill ...
+func TestReconcileBill(t *testing.T) {
+	store := &testOrderStore{
+		orders: map[string]*LocalOrder{
+			"1415640626": {OutTradeNo: "1415640626", TransactionID: "1001690740201411100005734289", TotalFee: 1, State: "SUCCESS"},
+			"1415635270": {OutTradeNo: "1415635270", TotalFee: 120, State: "REFUND", Refunds: []*LocalRefund{
+				{OutRefundNo: "1415635270", RefundFee: 120, Status: "SUCCESS"},
+			}},
+		},
+		paidAt: map[string]time.Time{
+			"1415640626": time.Date(2014, 11, 10, 16, 33, 45, 0, billLocation),
+			"1415635270": time.Date(2014, 11, 9, 10, 0, 0, 0, billLocation),
+		},
+	}
+	report := testReconcile(t, store)
+	if !report.Balanced() {
+		t.Fatalf("not balanced:%+v,%v", report.Diffs, report.SummaryErrors)
+	}
+	if report.Totals.Count != 2 || report.Totals.SettlementTotalFee != 121 || report.Totals.RefundFee != 120 {
+		t.Errorf("wrong totals:%+v", report.Totals)
+	}
+
+	store.orders["1415640626"].TotalFee = 2
+	store.orders["1415635270"].Refunds[0].Status = "PROCESSING"
+	store.orders["1415600000"] = &LocalOrder{OutTradeNo: "1415600000", TotalFee: 5, State: "SUCCESS"}
+	store.paidAt["1415600000"] = time.Date(2014, 11, 10, 23, 59, 59, 0, billLocation)
+	report = testReconcile(t, store)
+	kinds := map[ReconcileKind]string{}
+	for _, d := range report.Diffs {
+		kinds[d.Kind] = d.OutTradeNo
+	}
+	if len(report.Diffs) != 3 || kinds[ReconcileAmountMismatch] != "1415640626" ||
+		kinds[ReconcileRefundMismatch] != "1415635270" || kinds[ReconcileMissingRemotely] != "1415600000" {
+		t.Errorf("wrong diffs:%+v", kinds)
+	}
+
+	delete(store.orders, "1415640626")
+	delete(store.paidAt, "1415640626")
+	report = testReconcile(t, store)
+	if d := report.Diffs[0]; d.Kind != ReconcileMissingLocally || d.Remote == nil || d.Remote.TransactionID != "1001690740201411100005734289" {
+		t.Errorf("wrong diff:%+v", d)
+	}
+}
+
+// TestReconcileBill_Revoked ...
+func TestReconcileBill_Revoked(t *testing.T) {
+	store := &testOrderStore{
+		orders: map[string]*LocalOrder{
+			"1415640626": {OutTradeNo: "1415640626", TransactionID: "1001690740201411100005734280", TotalFee: 1, State: "SUCCESS"},
+		},
+		paidAt: map[string]time.Time{
+			"1415640626": time.Date(2014, 11, 10, 16, 33, 45, 0, billLocation),
+		},
+	}
+	reader, e := NewBillReader(strings.NewReader(testBill))
+	if e != nil {
+		t.Fatal(e)
+	}
+	report, e := ReconcileBill(context.Background(), time.Date(2014, 11, 10, 0, 0, 0, 0, billLocation), reader, store)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if d := report.Diffs[0]; d.Kind != ReconcileTransactionMismatch {
+		t.Errorf("wrong diff:%+v", d)
+	}
+
+	reader, _ = NewBillReader(strings.NewReader(strings.Replace(testBill, "`MICROPAY,`SUCCESS,", "`MICROPAY,`REVOKED,", 1)))
+	report, e = ReconcileBill(context.Background(), time.Date(2014, 11, 10, 0, 0, 0, 0, billLocation), reader, store)
+	if e != nil {
+		t.Fatal(e)
+	}
+	var kinds []ReconcileKind
+	for _, d := range report.Diffs {
+		if d.OutTradeNo == "1415640626" {
+			kinds = append(kinds, d.Kind)
+		}
+	}
+	if len(kinds) != 1 || kinds[0] != ReconcileStatusMismatch {
+		t.Errorf("revoked order should be reported once:%v", kinds)
+	}
+}
+
+// TestPayment_Reconcile ...
+func TestPayment_Reconcile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<xml><return_code><![CDATA[FAIL]]></return_code><return_msg><![CDATA[No Bill Exist]]></return_msg><error_code><![CDATA[20002]]></error_code></xml>`))
+	}))
+	defer server.Close()
+	payment := NewPayment(&PaymentProperty{AppID: "wx2421b1c4370ec43b", MchID: "10000100", Key: "192006250b4c09247ec02edce69f6a2d"},
+		PaymentRemote(server.URL))
+	store := &testOrderStore{
+		orders: map[string]*LocalOrder{
+			"1415640626": {OutTradeNo: "1415640626", TotalFee: 1, State: "SUCCESS"},
+		},
+		paidAt: map[string]time.Time{
+			"1415640626": time.Date(2014, 11, 10, 16, 33, 45, 0, billLocation),
+		},
+	}
+	date := time.Date(2014, 11, 10, 0, 0, 0, 0, billLocation)
+	report, e := payment.Reconcile(context.Background(), date, store)
+	if e != nil {
+		t.Fatal(e)
+	}
+	if len(report.Diffs) != 1 || report.Diffs[0].Kind != ReconcileMissingRemotely || len(report.SummaryErrors) != 0 {
+		t.Errorf("wrong report:%+v,%v", report.Diffs, report.SummaryErrors)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, e := payment.Reconcile(ctx, date, store); e == nil {
+		t.Error("canceled reconcile should not download")
+	}
+}
@@ -0,0 +1,139 @@
+package wego
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/godcong/wego/cache"
+	"golang.org/x/xerrors"
+)
+
+// refreshWaitInterval poll interval while another process is refreshing
+const refreshWaitInterval = 100 * time.Millisecond
+
+type flightCall struct {
+	wg  sync.WaitGroup
+	val string
+	err error
+}
+
+// flightGroup run only one call for a key at the same time
+type flightGroup struct {
+	mu sync.Mutex
+	m  map[string]*flightCall
+}
+
+// Do ...
+func (g *flightGroup) Do(key string, fn func() (string, error)) (string, error) {
+	g.mu.Lock()
+	if g.m == nil {
+		g.m = make(map[string]*flightCall)
+	}
+	if c, b := g.m[key]; b {
+		g.mu.Unlock()
+		c.wg.Wait()
+		return c.val, c.err
+	}
+	c := new(flightCall)
+	c.wg.Add(1)
+	g.m[key] = c
+	g.mu.Unlock()
+
+	c.val, c.err = fn()
+	c.wg.Done()
+
+	g.mu.Lock()
+	delete(g.m, key)
+	g.mu.Unlock()
+	return c.val, c.err
+}
+
+// refreshGroups the flight group of each cache,
+// the same key in different caches is refreshed and cached by each of them
+var refreshGroups sync.Map
+
+// cacheFlightGroup the caches are compared as interface,they are pointers in this package
+func cacheFlightGroup(c cache.Cache) *flightGroup {
+	g, _ := refreshGroups.LoadOrStore(c, new(flightGroup))
+	return g.(*flightGroup)
+}
+
+// refreshFunc fetch a new value with its ttl seconds,the value is not cached when ttl is 0
+type refreshFunc func() (val string, ttl int64, e error)
+
+// loadOrRefresh get the cached value or fetch a new one,
+// only one fetch runs for a key of the cache in process,and across processes when lockTTL > 0.
+// a failed cache is returned as error,it is not treated as a miss to fetch again
+func loadOrRefresh(c cache.Cache, key string, refresh bool, lockTTL int64, fetch refreshFunc) (string, error) {
+	cc := cache.NewContextCache(c)
+	if !refresh {
+		v, e := cachedString(cc, key)
+		if e != nil || v != "" {
+			return v, e
+		}
+	}
+	return cacheFlightGroup(c).Do(key, func() (string, error) {
+		stale, e := cachedString(cc, key)
+		if e != nil {
+			return "", e
+		}
+		if !refresh && stale != "" {
+			return stale, nil
+		}
+		if locker, b := c.(cache.Locker); b && lockTTL > 0 {
+			unlock, locked := locker.Lock(key+".lock", lockTTL)
+			if !locked {
+				if v := waitRefreshed(cc, key, stale, lockTTL); v != "" {
+					return v, nil
+				}
+			} else {
+				defer unlock()
+			}
+		}
+		val, ttl, e := fetch()
+		if e != nil {
+			return "", e
+		}
+		if ttl > 0 {
+			if e := cc.Set(context.Background(), key, []byte(val), time.Duration(ttl)*time.Second); e != nil {
+				log.Error(e)
+			}
+		}
+		return val, nil
+	})
+}
+
+// waitRefreshed wait the other process set a new value
+func waitRefreshed(c cache.ContextCache, key, stale string, ttl int64) string {
+	deadline := time.Now().Add(time.Duration(ttl) * time.Second)
+	for time.Now().Before(deadline) {
+		if v, _ := cachedString(c, key); v != "" && v != stale {
+			return v
+		}
+		time.Sleep(refreshWaitInterval)
+	}
+	return ""
+}
+
+// useCache return the first cache was set,or the default cache
+func useCache(caches ...cache.Cache) cache.Cache {
+	for _, c := range caches {
+		if c != nil {
+			return c
+		}
+	}
+	return cache.DefaultCache()
+}
+
+// cachedString return empty string when the key was not found
+func cachedString(c cache.ContextCache, key string) (string, error) {
+	v, e := c.Get(context.Background(), key)
+	if e == cache.ErrNotFound {
+		return "", nil
+	}
+	if e != nil {
+		return "", xerrors.Errorf("load cache %s: %w", key, e)
+	}
+	return string(v), nil
+}
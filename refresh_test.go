@@ -0,0 +1,154 @@
+package wego
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godcong/wego/cache"
+)
+
+// TestLoadOrRefresh ...
+func TestLoadOrRefresh(t *testing.T) {
+	c := cache.NewMapCache()
+	var fetched int32
+	fetch := func() (string, int64, error) {
+		time.Sleep(10 * time.Millisecond)
+		return "value" + strconv.Itoa(int(atomic.AddInt32(&fetched, 1))), 60, nil
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, e := loadOrRefresh(c, "single", false, 0, fetch); e != nil || v != "value1" {
+				t.Error(v, e)
+			}
+		}()
+	}
+	wg.Wait()
+	if fetched != 1 {
+		t.Errorf("fetched %d times", fetched)
+	}
+}
+
+// TestLoadOrRefresh_Caches ...
+func TestLoadOrRefresh_Caches(t *testing.T) {
+	c1, c2 := cache.NewMapCache(), cache.NewMapCache()
+	fetch := func() (string, int64, error) {
+		time.Sleep(20 * time.Millisecond)
+		return "value", 60, nil
+	}
+	var wg sync.WaitGroup
+	for _, c := range []*cache.MapCache{c1, c2, c1, c2} {
+		wg.Add(1)
+		go func(c cache.Cache) {
+			defer wg.Done()
+			if v, e := loadOrRefresh(c, "shared", false, 0, fetch); e != nil || v != "value" {
+				t.Error(v, e)
+			}
+		}(c)
+	}
+	wg.Wait()
+	if !c1.Has("shared") || !c2.Has("shared") {
+		t.Error("the value should be cached in each cache")
+	}
+}
+
+// TestLoadOrRefresh_Lock ...
+func TestLoadOrRefresh_Lock(t *testing.T) {
+	c := cache.NewMapCache()
+	c.Set("locked", "stale")
+	unlock, locked := c.Lock("locked.lock", 5)
+	if !locked {
+		t.Fatal("lock failed")
+	}
+	go func() {
+		//the other process refresh the value
+		time.Sleep(50 * time.Millisecond)
+		c.Set("locked", "fresh")
+		unlock()
+	}()
+
+	v, e := loadOrRefresh(c, "locked", true, 5, func() (string, int64, error) {
+		t.Error("should wait the lock owner")
+		return "", 0, nil
+	})
+	if e != nil || v != "fresh" {
+		t.Error(v, e)
+	}
+}
+
+type brokenCache struct {
+	*cache.MapCache
+}
+
+func (c brokenCache) Context() cache.ContextCache {
+	return brokenContextCache{}
+}
+
+type brokenContextCache struct{}
+
+func (c brokenContextCache) Get(ctx context.Context, key string) ([]byte, error) {
+	return nil, errors.New("connection refused")
+}
+
+func (c brokenContextCache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	return errors.New("connection refused")
+}
+
+func (c brokenContextCache) Delete(ctx context.Context, key string) error {
+	return errors.New("connection refused")
+}
+
+// TestLoadOrRefresh_CacheError ...
+func TestLoadOrRefresh_CacheError(t *testing.T) {
+	_, e := loadOrRefresh(brokenCache{cache.NewMapCache()}, "broken", false, 0, func() (string, int64, error) {
+		t.Error("a failed cache should not fetch")
+		return "", 0, nil
+	})
+	if e == nil {
+		t.Error("want error")
+	}
+}
+
+// TestJSSDK_TicketTypes ...
+func TestJSSDK_TicketTypes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		_, _ = w.Write([]byte(`{"errcode":0,"ticket":"` + r.URL.Query().Get("type") + `","expires_in":7200}`))
+	}))
+	defer server.Close()
+	redirect := func(next RoundTrip) RoundTrip {
+		return func(ctx context.Context, content *RequestContent) Responder {
+			content.URL = server.URL + ticketGetTicket
+			return next(ctx, content)
+		}
+	}
+	token := NewAccessToken(&AccessTokenProperty{AppID: "jssdk"}, AccessTokenSource(NewStaticTokenSource("token", 7200)))
+	jssdk := NewJSSDK(&JSSDKProperty{AppID: "jssdk"}, JSSDKAccessToken(token), JSSDKCache(cache.NewMapCache()), JSSDKMiddleware(redirect))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for _, typ := range []string{"jsapi", "wx_card"} {
+			wg.Add(1)
+			go func(typ string) {
+				defer wg.Done()
+				if v := jssdk.GetTicket(typ, false); v != typ {
+					t.Errorf("%s got ticket %s", typ, v)
+				}
+			}(typ)
+		}
+	}
+	wg.Wait()
+	if jssdk.getCacheKey("jsapi") == jssdk.getCacheKey("wx_card") {
+		t.Error("ticket types should not share a key")
+	}
+}
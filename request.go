@@ -278,7 +278,7 @@ func BuildRequester(req *http.Request) Requester {
 		return ErrRequest(err)
 	}
 
-	log.Debug("request:", string(body[:128]), len(body)) //max 128 char
+	log.Debug("request:", string(maxBody(body, 128)), len(body)) //max 128 char
 	if strings.Index(ct, "xml") != -1 ||
 		bytes.Index(body, []byte("<xml")) != -1 {
 		return XMLRequest(body)
@@ -1,13 +1,14 @@
 package wego
 
 import (
+	"bufio"
 	"bytes"
 	"encoding/xml"
 	"github.com/godcong/wego/util"
 	"github.com/json-iterator/go"
 	"golang.org/x/text/transform"
-	"golang.org/x/xerrors"
 	"io"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"strings"
@@ -25,6 +26,12 @@ type Response struct {
 	bytes  []byte
 	reader io.ReadCloser
 	err    error
+	header http.Header
+}
+
+// Header the http header of response,nil when it was not built from http response
+func (r *Response) Header() http.Header {
+	return r.header
 }
 
 // Type ...
@@ -32,13 +39,19 @@ func (r *Response) Type() BodyType {
 	return BodyTypeNone
 }
 
-// Read ...
+// Read read the body,the bytes are read when the response was built from http
 func (r *Response) Read(p []byte) (n int, err error) {
+	if r.reader == nil {
+		r.reader = ioutil.NopCloser(bytes.NewReader(r.bytes))
+	}
 	return r.reader.Read(p)
 }
 
 // Close ...
 func (r *Response) Close() error {
+	if r.reader == nil {
+		return nil
+	}
 	return r.reader.Close()
 }
 
@@ -95,17 +108,16 @@ func (r *xmlResponse) Result() (util.Map, error) {
 	return r.data, e
 }
 
-// Error ...
+// Error return *APIError when return_code or result_code is FAIL
 func (r *xmlResponse) Error() error {
-	var e ErrRes
 	if r.err != nil {
 		return r.err
 	}
-	_ = jsoniter.Unmarshal(r.bytes, &e)
-	if e.ErrCode != 0 {
-		return xerrors.Errorf("code:%d,msg:%s", e.ErrCode, e.ErrMsg)
+	m, e := r.Result()
+	if e != nil {
+		return nil
 	}
-	return nil
+	return xmlAPIError(r.bytes, m)
 }
 
 // jsonResponse ...
@@ -158,17 +170,16 @@ func (r *jsonResponse) Result() (util.Map, error) {
 	return r.data, e
 }
 
-// Error ...
+// Error return *APIError when errcode is not 0
 func (r *jsonResponse) Error() error {
-	var e ErrRes
 	if r.err != nil {
 		return r.err
 	}
-	_ = jsoniter.Unmarshal(r.bytes, &e)
-	if e.ErrCode != 0 {
-		return xerrors.Errorf("code:%d,msg:%s", e.ErrCode, e.ErrMsg)
+	m, e := r.Result()
+	if e != nil {
+		return nil
 	}
-	return nil
+	return jsonAPIError(r.bytes, m)
 }
 
 // ToMap ...
@@ -200,6 +211,7 @@ func (r *Response) Bytes() []byte {
 }
 
 // ErrRes ...
+// Deprecated: use APIError
 type ErrRes struct {
 	ErrCode int64
 	ErrMsg  string
@@ -237,6 +249,7 @@ func ResponseWriter(w http.ResponseWriter, responder Responder) error {
 
 // BuildResponder ...
 func BuildResponder(resp *http.Response) Responder {
+	defer resp.Body.Close()
 	ct := resp.Header.Get("Content-Type")
 	body, err := readBody(resp.Body)
 	if err != nil {
@@ -244,16 +257,79 @@ func BuildResponder(resp *http.Response) Responder {
 		return ErrResponder(err)
 	}
 
-	log.Info("response:", string(body[:128]), len(body)) //max 128 char
-	if resp.StatusCode == 200 {
+	log.Info("response:", string(maxBody(body, 128)), len(body)) //max 128 char
+	//api v3 response 204 without body
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		response := Response{
+			bytes:  body,
+			header: resp.Header,
+		}
 		if strings.Index(ct, "xml") != -1 ||
 			bytes.Index(body, []byte("<xml")) != -1 {
-			return XMLResponse(body)
+			return &xmlResponse{Response: response}
 		}
-		return JSONResponse(body)
+		return &jsonResponse{Response: response}
 	}
 	log.Error("error with " + resp.Status)
-	return ErrResponder(xerrors.New("error with code " + resp.Status))
+	return &Response{
+		err: &StatusError{
+			StatusCode: resp.StatusCode,
+			Status:     resp.Status,
+			Body:       body,
+		},
+		header: resp.Header,
+	}
+}
+
+// streamBody ...
+type streamBody struct {
+	io.Reader
+	io.Closer
+}
+
+// BuildStreamResponder the body of 2xx response is not read,except the xml error of pay api,
+// which is detected by the head of body
+func BuildStreamResponder(resp *http.Response) Responder {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return BuildResponder(resp)
+	}
+	reader := bufio.NewReader(resp.Body)
+	head, _ := reader.Peek(64)
+	if bytes.HasPrefix(bytes.TrimSpace(head), []byte("<xml")) {
+		defer resp.Body.Close()
+		body, e := readBody(ioutil.NopCloser(reader))
+		if e != nil {
+			return ErrResponder(e)
+		}
+		log.Info("response:", string(maxBody(body, 128)), len(body))
+		return &xmlResponse{Response: Response{
+			bytes:  body,
+			header: resp.Header,
+		}}
+	}
+	return &Response{
+		reader: &streamBody{Reader: reader, Closer: resp.Body},
+		header: resp.Header,
+	}
+}
+
+// StatusError response with a non 2xx status code
+type StatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+// Error ...
+func (e *StatusError) Error() string {
+	return "error with code " + e.Status
+}
+
+func maxBody(body []byte, max int) []byte {
+	if len(body) > max {
+		return body[:max]
+	}
+	return body
 }
 
 // SaveTo ...
@@ -280,6 +356,7 @@ func SaveTo(response Responder, path string) error {
 }
 
 // SaveEncodingTo ...
+// Deprecated: use BillReader or FundFlowReader,the GBK bill is decoded by them
 func SaveEncodingTo(response Responder, path string, t transform.Transformer) (err error) {
 	var file *os.File
 	file, err = os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_SYNC, os.ModePerm)
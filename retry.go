@@ -0,0 +1,189 @@
+package wego
+
+import (
+	"context"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/godcong/wego/util"
+	"golang.org/x/xerrors"
+)
+
+// RetryClassifier check the response of a request can be retried
+type RetryClassifier func(content *RequestContent, resp Responder) bool
+
+// RetryPolicy retry with exponential backoff and jitter
+type RetryPolicy struct {
+	MaxAttempts     int
+	InitialInterval time.Duration
+	MaxInterval     time.Duration
+	Multiplier      float64
+	Jitter          float64
+	MaxElapsedTime  time.Duration
+	Classifier      RetryClassifier
+	// IdempotentKeys uri suffix => the keys must be carried before retry
+	IdempotentKeys map[string][]string
+}
+
+/*DefaultIdempotentKeys 非幂等接口重试时必须携带的单号 */
+var DefaultIdempotentKeys = map[string][]string{
+	payMicroPay:                         {"out_trade_no"},
+	payRefund:                           {"out_refund_no"},
+	payReverse:                          {"out_trade_no|transaction_id"},
+	mmpaymkttransfersPromotionTransfers: {"partner_trade_no"},
+	mmpaysptransPayBank:                 {"partner_trade_no"},
+	mmpaymkttransfersSendRedPack:        {"mch_billno"},
+	mmpaymkttransfersSendGroupRedPack:   {"mch_billno"},
+}
+
+// DefaultRetryPolicy ...
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:     3,
+		InitialInterval: 200 * time.Millisecond,
+		MaxInterval:     2 * time.Second,
+		Multiplier:      2,
+		Jitter:          0.2,
+		MaxElapsedTime:  10 * time.Second,
+		Classifier:      DefaultRetryClassifier,
+		IdempotentKeys:  DefaultIdempotentKeys,
+	}
+}
+
+// DefaultRetryClassifier retry with network error,5xx,errcode -1 and SYSTEMERROR
+func DefaultRetryClassifier(content *RequestContent, resp Responder) bool {
+	if e := resp.Error(); e != nil {
+		var se *StatusError
+		if xerrors.As(e, &se) {
+			return se.StatusCode >= 500
+		}
+		var ne net.Error
+		if xerrors.As(e, &ne) {
+			return true
+		}
+		return IsSystemBusy(e)
+	}
+	return false
+}
+
+// Do run the round trip until success or not retryable
+func (p *RetryPolicy) Do(ctx context.Context, content *RequestContent, fn RoundTrip) Responder {
+	start := time.Now()
+	resp := fn(ctx, content)
+	for attempt := 1; attempt < p.MaxAttempts; attempt++ {
+		if ctx.Err() != nil || !p.retryable(content, resp) {
+			return resp
+		}
+		wait := p.backoff(attempt)
+		if p.MaxElapsedTime > 0 && time.Since(start)+wait > p.MaxElapsedTime {
+			return resp
+		}
+		log.Debugf("retry %s after %v,attempt:%d", content.URL, wait, attempt)
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return resp
+		case <-timer.C:
+		}
+		resp = fn(ctx, content)
+	}
+	return resp
+}
+
+func (p *RetryPolicy) retryable(content *RequestContent, resp Responder) bool {
+	if !p.idempotent(content) {
+		return false
+	}
+	classifier := p.Classifier
+	if classifier == nil {
+		classifier = DefaultRetryClassifier
+	}
+	return classifier(content, resp)
+}
+
+// idempotent check the body can be replayed and carried the needed keys
+func (p *RetryPolicy) idempotent(content *RequestContent) bool {
+	if !replayable(content) {
+		return false
+	}
+	keys, b := p.idempotentKeys(content.URL)
+	if !b {
+		return true
+	}
+	m := bodyMap(content)
+	if m == nil {
+		return false
+	}
+	for _, key := range keys {
+		if !hasAny(m, strings.Split(key, "|")) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *RetryPolicy) idempotentKeys(uri string) ([]string, bool) {
+	if u, e := url.Parse(uri); e == nil {
+		uri = u.Path
+	}
+	for suffix, keys := range p.IdempotentKeys {
+		if strings.HasSuffix(uri, suffix) {
+			return keys, true
+		}
+	}
+	return nil, false
+}
+
+func (p *RetryPolicy) backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	wait := float64(p.InitialInterval) * math.Pow(multiplier, float64(attempt-1))
+	if p.MaxInterval > 0 && wait > float64(p.MaxInterval) {
+		wait = float64(p.MaxInterval)
+	}
+	if p.Jitter > 0 {
+		delta := p.Jitter * wait
+		wait = wait - delta + rand.Float64()*2*delta
+	}
+	return time.Duration(wait)
+}
+
+// replayable the body can be built again
+func replayable(content *RequestContent) bool {
+	if content.Body != nil {
+		if _, b := content.Body.BodyInstance.(io.Reader); b {
+			return false
+		}
+	}
+	return true
+}
+
+func bodyMap(content *RequestContent) util.Map {
+	if content.Body == nil {
+		return nil
+	}
+	switch v := content.Body.BodyInstance.(type) {
+	case util.Map:
+		return v
+	case util.MapAble:
+		return v.ToMap()
+	}
+	return nil
+}
+
+func hasAny(m util.Map, keys []string) bool {
+	for _, key := range keys {
+		if m.GetString(key) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,60 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godcong/wego/util"
+)
+
+func testRetryPolicy() *RetryPolicy {
+	policy := DefaultRetryPolicy()
+	policy.InitialInterval = time.Millisecond
+	policy.MaxInterval = 5 * time.Millisecond
+	return policy
+}
+
+// TestRetryPolicy_Do ...
+func TestRetryPolicy_Do(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&count, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`<xml><return_code>SUCCESS</return_code></xml>`))
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientRetryPolicy(testRetryPolicy()))
+	resp := client.Post(context.Background(), server.URL+payUnifiedOrder, nil, util.Map{"out_trade_no": "1"})
+	if resp.Error() != nil || atomic.LoadInt32(&count) != 3 {
+		t.Error(resp.Error(), count)
+	}
+}
+
+// TestRetryPolicy_Idempotent ...
+func TestRetryPolicy_Idempotent(t *testing.T) {
+	var count int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&count, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := NewClient(ClientRetryPolicy(testRetryPolicy()))
+	resp := client.Post(context.Background(), server.URL+payMicroPay, nil, util.Map{"auth_code": "1"})
+	if resp.Error() == nil || atomic.LoadInt32(&count) != 1 {
+		t.Error("micropay without out_trade_no should not retry", count)
+	}
+
+	atomic.StoreInt32(&count, 0)
+	resp = client.Post(context.Background(), server.URL+payMicroPay, nil, util.Map{"auth_code": "1", "out_trade_no": "2"})
+	if resp.Error() == nil || atomic.LoadInt32(&count) != 3 {
+		t.Error("micropay with out_trade_no should retry", count)
+	}
+}
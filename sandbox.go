@@ -3,6 +3,7 @@ package wego
 import (
 	"crypto/md5"
 	"fmt"
+	"github.com/godcong/wego/cache"
 	"github.com/godcong/wego/util"
 	"strings"
 )
@@ -10,8 +11,10 @@ import (
 // Sandbox ...
 type Sandbox struct {
 	*SandboxProperty
-	subMchID string
-	subAppID string
+	subMchID    string
+	subAppID    string
+	cache       cache.Cache
+	cachePrefix string
 }
 
 // NewSandbox ...
@@ -35,7 +38,7 @@ func (obj *Sandbox) parse(options ...SandboxOption) {
 
 func (obj *Sandbox) getCacheKey() string {
 	name := strings.Join([]string{obj.AppID, obj.MchID}, ".")
-	return "godcong.wego.payment.sandbox." + fmt.Sprintf("%x", md5.Sum([]byte(name)))
+	return obj.cachePrefix + "godcong.wego.payment.sandbox." + fmt.Sprintf("%x", md5.Sum([]byte(name)))
 }
 
 // SignKey ...
@@ -1,6 +1,7 @@
 package wego
 
 import (
+	"context"
 	"github.com/godcong/wego/util"
 )
 
@@ -15,6 +16,7 @@ type TicketRes struct {
 /*Ticket Ticket */
 type Ticket struct {
 	*AccessToken
+	middlewares []Middleware
 }
 
 /*NewTicket NewTicket */
@@ -31,7 +33,8 @@ func NewTicket(accessToken *AccessToken) *Ticket {
 func (t *Ticket) Get(s string) Responder {
 	log.Debug("Ticket|Get", s)
 	p := t.KeyMap().Set("type", s)
-	return GetTicket(p)
+	client := NewClient(ClientMiddleware(t.middlewares...))
+	return client.Get(context.Background(), util.URL(apiWeixin, ticketGetTicket), p)
 }
 
 // GetTicketRes ...
@@ -0,0 +1,196 @@
+package wego
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/godcong/wego/cache"
+)
+
+// TokenManager refresh the registered access tokens and tickets in background,
+// before they are expired
+type TokenManager struct {
+	advance       time.Duration
+	jitter        float64
+	retryInterval time.Duration
+	onError       func(name string, e error)
+	targets       []*refreshTarget
+	ctx           context.Context
+	cancel        context.CancelFunc
+	wg            sync.WaitGroup
+	mu            sync.Mutex
+}
+
+// refreshTarget refresh returns the life seconds of the new value,
+// remaining returns the life of the cached value to schedule the first refresh
+type refreshTarget struct {
+	name      string
+	refresh   func() (int64, error)
+	remaining func() (time.Duration, bool)
+}
+
+const defaultTokenManagerAdvance = 60 * time.Second
+const defaultTokenManagerJitter = 0.1
+const defaultTokenManagerRetryInterval = 30 * time.Second
+
+// NewTokenManager ...
+func NewTokenManager(options ...TokenManagerOption) *TokenManager {
+	manager := &TokenManager{
+		advance:       defaultTokenManagerAdvance,
+		jitter:        defaultTokenManagerJitter,
+		retryInterval: defaultTokenManagerRetryInterval,
+	}
+	for _, o := range options {
+		o(manager)
+	}
+	return manager
+}
+
+// AddAccessToken register an access token
+func (m *TokenManager) AddAccessToken(token *AccessToken) *TokenManager {
+	return m.add(&refreshTarget{
+		name:      "access_token." + token.AppID,
+		remaining: cachedRemaining(token.Cache(), token.getCacheKey()),
+		refresh: func() (int64, error) {
+			t, e := token.loadToken(true, false)
+			if e != nil {
+				return 0, e
+			}
+			return tokenLife(t.ExpiresIn), nil
+		},
+	})
+}
+
+// AddTicket register a ticket of jssdk,typ: jsapi,wx_card
+func (m *TokenManager) AddTicket(jssdk *JSSDK, typ string) *TokenManager {
+	return m.add(&refreshTarget{
+		name:      "ticket." + typ + "." + jssdk.getID(),
+		remaining: cachedRemaining(useCache(jssdk.cache), jssdk.getCacheKey(typ)),
+		refresh: func() (int64, error) {
+			_, life, e := jssdk.loadTicket(typ, true)
+			return life, e
+		},
+	})
+}
+
+// AddPaymentV3 register the platform certificates of payment v3
+func (m *TokenManager) AddPaymentV3(payment *PaymentV3) *TokenManager {
+	return m.add(&refreshTarget{
+		name: "payment_v3.certificates." + payment.MchID,
+		refresh: func() (int64, error) {
+			_, e := payment.DownloadCertificates(context.Background())
+			return paymentV3CertificateLife, e
+		},
+	})
+}
+
+// cachedRemaining the ttl of key,not found when the cache can not tell it
+func cachedRemaining(c cache.Cache, key string) func() (time.Duration, bool) {
+	return func() (time.Duration, bool) {
+		if expirer, b := c.(cache.Expirer); b {
+			return expirer.TTL(key)
+		}
+		return 0, false
+	}
+}
+
+// add the target added after Start is scheduled at once
+func (m *TokenManager) add(target *refreshTarget) *TokenManager {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.targets = append(m.targets, target)
+	if m.cancel != nil {
+		m.wg.Add(1)
+		go m.run(m.ctx, target)
+	}
+	return m
+}
+
+// Start run a goroutine for each registered target
+func (m *TokenManager) Start(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.cancel != nil {
+		return
+	}
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	for _, target := range m.targets {
+		m.wg.Add(1)
+		go m.run(m.ctx, target)
+	}
+}
+
+// Stop stop all the goroutines and wait them exit
+func (m *TokenManager) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
+	m.cancel = nil
+	m.mu.Unlock()
+	if cancel != nil {
+		cancel()
+		m.wg.Wait()
+	}
+}
+
+func (m *TokenManager) run(ctx context.Context, target *refreshTarget) {
+	defer m.wg.Done()
+	wait := m.first(target)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		wait = m.refresh(target)
+	}
+}
+
+// first the wait time before the first refresh,the value cached by other processes
+// is used until it is going to expire,so the restarted apps will not refresh all at once
+func (m *TokenManager) first(target *refreshTarget) time.Duration {
+	if target.remaining == nil {
+		return 0
+	}
+	ttl, found := target.remaining()
+	if !found || ttl <= 0 {
+		return 0
+	}
+	wait := ttl - m.advance
+	if wait <= 0 {
+		return 0
+	}
+	return m.withJitter(wait)
+}
+
+// refresh refresh the target and return the wait time before next refresh,
+// the old value is still cached and served when refresh failed
+func (m *TokenManager) refresh(target *refreshTarget) time.Duration {
+	life, e := target.refresh()
+	if e != nil {
+		log.Errorf("TokenManager refresh %s err:%+v", target.name, e)
+		if m.onError != nil {
+			m.onError(target.name, e)
+		}
+		return m.withJitter(m.retryInterval)
+	}
+	if life <= 0 {
+		life = tokenLife(0)
+	}
+	wait := time.Duration(life)*time.Second - m.advance
+	if wait < time.Second {
+		wait = time.Second
+	}
+	return m.withJitter(wait)
+}
+
+// withJitter spread the schedules of many apps
+func (m *TokenManager) withJitter(d time.Duration) time.Duration {
+	if m.jitter <= 0 {
+		return d
+	}
+	return d - time.Duration(rand.Float64()*m.jitter*float64(d))
+}
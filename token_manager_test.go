@@ -0,0 +1,83 @@
+package wego
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/godcong/wego/cache"
+)
+
+// TestTokenManager ...
+func TestTokenManager(t *testing.T) {
+	var fail, hits int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(`{"access_token":"managed","expires_in":7200}`))
+	}))
+	defer server.Close()
+
+	token := NewAccessToken(&AccessTokenProperty{AppID: "manager", AppSecret: "secret"}, AccessTokenRemote(server.URL))
+	var failed int32
+	manager := NewTokenManager(TokenManagerJitter(0), TokenManagerRetryInterval(5*time.Millisecond),
+		TokenManagerOnError(func(name string, e error) {
+			atomic.AddInt32(&failed, 1)
+		})).AddAccessToken(token)
+
+	manager.Start(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	if v := token.GetToken(); v == nil || v.AccessToken != "managed" {
+		t.Fatal("token should be refreshed in background", v)
+	}
+	manager.Stop()
+
+	//the cached token is not refreshed again when restarted
+	atomic.StoreInt32(&hits, 0)
+	manager.Start(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	manager.Stop()
+	if atomic.LoadInt32(&hits) != 0 {
+		t.Error("cached token should not be refreshed at start", hits)
+	}
+
+	atomic.StoreInt32(&fail, 1)
+	manager = NewTokenManager(TokenManagerJitter(0), TokenManagerRetryInterval(5*time.Millisecond), TokenManagerAdvance(3*time.Hour),
+		TokenManagerOnError(func(name string, e error) {
+			atomic.AddInt32(&failed, 1)
+		})).AddAccessToken(token)
+	manager.Start(context.Background())
+	time.Sleep(50 * time.Millisecond)
+	manager.Stop()
+	if atomic.LoadInt32(&failed) < 2 {
+		t.Error("error callback should be called", failed)
+	}
+	if v := token.GetToken(); v == nil || v.AccessToken != "managed" {
+		t.Error("old token should be served", v)
+	}
+}
+
+// TestTokenManager_AddAfterStart ...
+func TestTokenManager_AddAfterStart(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"late","expires_in":7200}`))
+	}))
+	defer server.Close()
+
+	c := cache.NewMapCache()
+	token := NewAccessToken(&AccessTokenProperty{AppID: "late", AppSecret: "secret"}, AccessTokenRemote(server.URL), AccessTokenCache(c))
+	manager := NewTokenManager(TokenManagerJitter(0))
+	manager.Start(context.Background())
+	defer manager.Stop()
+	manager.AddAccessToken(token)
+	time.Sleep(50 * time.Millisecond)
+	if !c.Has(token.getCacheKey()) {
+		t.Error("token added after start should be refreshed")
+	}
+}
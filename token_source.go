@@ -0,0 +1,153 @@
+package wego
+
+import (
+	"context"
+	"os"
+
+	"github.com/godcong/wego/cache"
+	"golang.org/x/xerrors"
+)
+
+// ErrTokenNotCached the token source never fetch and nothing was cached
+var ErrTokenNotCached = xerrors.New("token not cached")
+
+// TokenSource fetch a new access token
+type TokenSource interface {
+	Token() (*Token, error)
+}
+
+// ForceTokenSource token source which can invalidate the old token when refresh
+type ForceTokenSource interface {
+	TokenSource
+	ForceToken() (*Token, error)
+}
+
+// cachedTokenSource the token was read from cache,it should not be cached again.
+// bind read the cache of the AccessToken
+type cachedTokenSource interface {
+	TokenSource
+	bind(token *AccessToken) TokenSource
+}
+
+// TokenSourceFunc ...
+type TokenSourceFunc func() (*Token, error)
+
+// Token ...
+func (f TokenSourceFunc) Token() (*Token, error) {
+	return f()
+}
+
+/*defaultTokenSource 通过/cgi-bin/token或/cgi-bin/stable_token获取 */
+type defaultTokenSource struct {
+	*AccessToken
+}
+
+// Token ...
+func (s *defaultTokenSource) Token() (*Token, error) {
+	return s.token(false)
+}
+
+// ForceToken force refresh the stable token,same as Token with /cgi-bin/token
+func (s *defaultTokenSource) ForceToken() (*Token, error) {
+	return s.token(true)
+}
+
+func (s *defaultTokenSource) token(force bool) (*Token, error) {
+	if s.Stable {
+		return requestStableToken(s.client(ClientBodyType(BodyTypeJSON)), s.TokenURL(), s.AccessTokenProperty, force)
+	}
+	return requestToken(s.client(), s.TokenURL(), s.AccessTokenProperty)
+}
+
+type brokerTokenSource struct {
+	url    string
+	client *Client
+}
+
+// NewBrokerTokenSource get token from a central token server,
+// the server should response the token json: {"access_token":"","expires_in":7200}
+func NewBrokerTokenSource(url string, options ...ClientOption) TokenSource {
+	return &brokerTokenSource{
+		url:    url,
+		client: NewClient(append([]ClientOption{ClientBodyType(BodyTypeJSON)}, options...)...),
+	}
+}
+
+// Token ...
+func (s *brokerTokenSource) Token() (*Token, error) {
+	var t Token
+	resp := s.client.Get(context.Background(), s.url, nil)
+	if e := resp.Error(); e != nil {
+		return nil, e
+	}
+	if e := resp.Unmarshal(&t); e != nil {
+		return nil, e
+	}
+	if t.AccessToken == "" {
+		return nil, xerrors.New(tokenNil)
+	}
+	return &t, nil
+}
+
+// NewStaticTokenSource always return the same token
+func NewStaticTokenSource(token string, expiresIn int64) TokenSource {
+	return TokenSourceFunc(func() (*Token, error) {
+		return &Token{
+			AccessToken: token,
+			ExpiresIn:   expiresIn,
+		}, nil
+	})
+}
+
+// NewEnvTokenSource read the token from environment variable
+func NewEnvTokenSource(name string) TokenSource {
+	return TokenSourceFunc(func() (*Token, error) {
+		token := os.Getenv(name)
+		if token == "" {
+			return nil, xerrors.Errorf("empty environment variable:%s", name)
+		}
+		return &Token{
+			AccessToken: token,
+		}, nil
+	})
+}
+
+type cacheTokenSource struct {
+	key   string
+	cache cache.Cache
+}
+
+// NewCacheTokenSource never fetch the token,only read it from the cache of AccessToken.
+// key is the cache key written by the other service,the cache prefix of AccessToken is prepended,
+// empty means the key of AccessToken itself
+func NewCacheTokenSource(key string) TokenSource {
+	return &cacheTokenSource{
+		key: key,
+	}
+}
+
+// Token ...
+func (s *cacheTokenSource) Token() (*Token, error) {
+	if s.key == "" {
+		return nil, ErrTokenNotCached
+	}
+	v, b := useCache(s.cache).Get(s.key).(string)
+	if !b || v == "" {
+		return nil, ErrTokenNotCached
+	}
+	if token, e := ParseToken(v); e == nil && token.AccessToken != "" {
+		return token, nil
+	}
+	return &Token{AccessToken: v}, nil
+}
+
+func (s *cacheTokenSource) bind(token *AccessToken) TokenSource {
+	key := token.getCacheKey()
+	if s.key != "" {
+		key = token.cachePrefix + s.key
+	}
+	return &cacheTokenSource{
+		key:   key,
+		cache: token.Cache(),
+	}
+}
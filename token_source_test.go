@@ -0,0 +1,77 @@
+package wego
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/godcong/wego/cache"
+)
+
+// TestTokenSource ...
+func TestTokenSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"access_token":"broker","expires_in":7200}`))
+	}))
+	defer server.Close()
+
+	token := NewAccessToken(&AccessTokenProperty{AppID: "broker"}, AccessTokenCache(cache.NewMapCache()),
+		AccessTokenSource(NewBrokerTokenSource(server.URL)))
+	if v := token.GetToken(); v == nil || v.AccessToken != "broker" {
+		t.Error("wrong broker token", v)
+	}
+
+	token = NewAccessToken(&AccessTokenProperty{AppID: "static"}, AccessTokenCache(cache.NewMapCache()),
+		AccessTokenSource(NewStaticTokenSource("static", 7200)))
+	if v := token.GetToken(); v == nil || v.AccessToken != "static" {
+		t.Error("wrong static token", v)
+	}
+
+	c := cache.NewMapCache()
+	token = NewAccessToken(&AccessTokenProperty{AppID: "cache"}, AccessTokenCache(c),
+		AccessTokenSource(NewCacheTokenSource("other.service.token")))
+	if v := token.GetToken(); v != nil {
+		t.Error("cache source should never fetch", v)
+	}
+	c.Set("other.service.token", "shared")
+	if v := token.GetToken(); v == nil || v.AccessToken != "shared" {
+		t.Error("wrong cached token", v)
+	}
+	if c.Has(token.getCacheKey()) {
+		t.Error("token from cache source should not be cached again")
+	}
+}
+
+// TestCacheTokenSource ...
+func TestCacheTokenSource(t *testing.T) {
+	c := cache.NewMapCache()
+	token := NewAccessToken(&AccessTokenProperty{AppID: "own"}, AccessTokenCache(c), AccessTokenSource(NewCacheTokenSource("")))
+	if v := token.GetRefreshToken(); v != nil {
+		t.Error("cache source should never fetch", v)
+	}
+	c.Set(token.getCacheKey(), "own")
+	if v := token.GetRefreshToken(); v == nil || v.AccessToken != "own" {
+		t.Error("empty key should read the key of AccessToken", v)
+	}
+
+	token = NewAccessToken(&AccessTokenProperty{AppID: "other"}, AccessTokenCache(c), AccessTokenSource(NewCacheTokenSource("isolated.service.token")))
+	c.Set("isolated.service.token", "isolated")
+	if v := token.GetToken(); v == nil || v.AccessToken != "isolated" {
+		t.Error("cache of AccessToken should be read", v)
+	}
+}
+
+// TestCacheTokenSource_Prefix ...
+func TestCacheTokenSource_Prefix(t *testing.T) {
+	c := cache.NewMapCache()
+	token := NewAccessToken(&AccessTokenProperty{AppID: "prefixed"}, AccessTokenCache(c), AccessTokenCachePrefix("prod:"),
+		AccessTokenSource(NewCacheTokenSource("shared.service.token")))
+	c.Set("shared.service.token", "unprefixed")
+	if v := token.GetToken(); v != nil {
+		t.Error("the key without prefix should not be read", v)
+	}
+	c.Set("prod:shared.service.token", "prefixed")
+	if v := token.GetToken(); v == nil || v.AccessToken != "prefixed" {
+		t.Error("wrong prefixed token", v)
+	}
+}